@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"sso/internal/lib/phone"
+	"sso/internal/storage/sqlite"
+)
+
+// normalize-phones rewrites phones of existing users in E.164 format, the way the service stores new ones.
+// Users whose phones normalize to the same number can't be merged automatically: they are reported
+// and left unchanged, as well as phones that can't be parsed. Exits with status 1 if there are any.
+func main() {
+	var (
+		storagePath string
+		dryRun      bool
+	)
+
+	flag.StringVar(&storagePath, "storage-path", "", "path to storage")
+	flag.BoolVar(&dryRun, "dry-run", false, "report changes without applying them")
+	flag.Parse()
+
+	if storagePath == "" {
+		panic("storage-path is required")
+	}
+
+	storage, err := sqlite.New(storagePath)
+	if err != nil {
+		panic(err)
+	}
+	defer storage.Stop()
+
+	ctx := context.Background()
+
+	phones, err := storage.UserPhones(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	ids := make([]int64, 0, len(phones))
+	for id := range phones {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	owners := make(map[string][]int64)
+	problems := 0
+
+	for _, id := range ids {
+		normalized, err := phone.Normalize(phones[id])
+		if err != nil {
+			fmt.Printf("invalid: user %d, phone %q\n", id, phones[id])
+			problems++
+
+			continue
+		}
+
+		owners[normalized] = append(owners[normalized], id)
+	}
+
+	updates := make(map[int64]string)
+
+	for normalized, owner := range owners {
+		if len(owner) > 1 {
+			fmt.Printf("collision: %s is the phone of users", normalized)
+			for _, id := range owner {
+				fmt.Printf(" %d (%q)", id, phones[id])
+			}
+			fmt.Println()
+			problems++
+
+			continue
+		}
+
+		if id := owner[0]; phones[id] != normalized {
+			updates[id] = normalized
+		}
+	}
+
+	fmt.Printf("%d of %d phones to normalize, %d problems\n", len(updates), len(phones), problems)
+
+	if !dryRun && len(updates) > 0 {
+		if err := storage.UpdateUserPhones(ctx, updates); err != nil {
+			panic(err)
+		}
+
+		fmt.Println("phones normalized")
+	}
+
+	if problems > 0 {
+		_ = storage.Stop()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"sso/internal/domain/models"
+	"sso/internal/lib/secret"
+	"sso/internal/storage/sqlite"
+	"strings"
+)
+
+const (
+	// clientIDSize and clientSecretSize are the numbers of random bytes in a client ID and a client secret.
+	clientIDSize     = 12
+	clientSecretSize = 32
+)
+
+// register-client registers an OpenID Connect client of the app and prints its ID and secret.
+// The secret is stored only as a hash, so it can't be shown again.
+// Public clients, such as single-page and mobile apps, get no secret and must use PKCE.
+func main() {
+	var (
+		storagePath  string
+		appID        int64
+		name         string
+		redirectList string
+		public       bool
+	)
+
+	flag.StringVar(&storagePath, "storage-path", "", "path to storage")
+	flag.Int64Var(&appID, "app-id", 0, "id of the app the client gets tokens of")
+	flag.StringVar(&name, "name", "", "name of the client shown on the sign-in page")
+	flag.StringVar(&redirectList, "redirect-uris", "", "comma-separated redirect URIs")
+	flag.BoolVar(&public, "public", false, "register a public client without a secret")
+	flag.Parse()
+
+	if storagePath == "" {
+		panic("storage-path is required")
+	}
+	if appID == 0 {
+		panic("app-id is required")
+	}
+	if name == "" {
+		panic("name is required")
+	}
+
+	var redirectURIs []string
+	for _, uri := range strings.Split(redirectList, ",") {
+		uri = strings.TrimSpace(uri)
+		if uri == "" {
+			continue
+		}
+		if u, err := url.Parse(uri); err != nil || !u.IsAbs() || u.Fragment != "" {
+			panic(fmt.Sprintf("invalid redirect uri %q", uri))
+		}
+
+		redirectURIs = append(redirectURIs, uri)
+	}
+	if len(redirectURIs) == 0 {
+		panic("redirect-uris are required")
+	}
+
+	clientID, err := secret.Generate(clientIDSize)
+	if err != nil {
+		panic(err)
+	}
+
+	client := models.OAuthClient{
+		ID:           clientID,
+		AppID:        appID,
+		Name:         name,
+		RedirectURIs: redirectURIs,
+	}
+
+	var clientSecret string
+	if !public {
+		clientSecret, err = secret.Generate(clientSecretSize)
+		if err != nil {
+			panic(err)
+		}
+
+		client.SecretHash = secret.Hash(clientSecret)
+	}
+
+	storage, err := sqlite.New(storagePath)
+	if err != nil {
+		panic(err)
+	}
+	defer storage.Stop()
+
+	ctx := context.Background()
+
+	if _, err := storage.App(ctx, appID); err != nil {
+		panic(err)
+	}
+
+	if err := storage.SaveOAuthClient(ctx, client); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("client_id: %s\n", clientID)
+	if clientSecret != "" {
+		fmt.Printf("client_secret: %s\n", clientSecret)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"sso/internal/domain/models"
+	"sso/internal/lib/phone"
+	"sso/internal/storage/sqlite"
+	"strings"
+)
+
+// set-roles replaces the roles of the user with the given phone, e.g. to appoint the first admin,
+// who can then manage roles of other users over REST. Every user keeps the user role.
+// The new roles get into the user's tokens on the next refresh.
+func main() {
+	var (
+		storagePath string
+		userPhone   string
+		roleList    string
+	)
+
+	flag.StringVar(&storagePath, "storage-path", "", "path to storage")
+	flag.StringVar(&userPhone, "phone", "", "phone of the user")
+	flag.StringVar(&roleList, "roles", "", "comma-separated roles, e.g. admin,editor")
+	flag.Parse()
+
+	if storagePath == "" {
+		panic("storage-path is required")
+	}
+	if userPhone == "" {
+		panic("phone is required")
+	}
+
+	normalized, err := phone.Normalize(userPhone)
+	if err != nil {
+		panic(err)
+	}
+
+	granted := map[string]bool{models.RoleUser: true}
+	for _, role := range strings.Split(roleList, ",") {
+		role = strings.TrimSpace(role)
+		if role == "" {
+			continue
+		}
+		if !models.IsRole(role) {
+			panic(fmt.Sprintf("unknown role %q", role))
+		}
+
+		granted[role] = true
+	}
+
+	roles := make([]string, 0, len(granted))
+	for role := range granted {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	storage, err := sqlite.New(storagePath)
+	if err != nil {
+		panic(err)
+	}
+	defer storage.Stop()
+
+	ctx := context.Background()
+
+	user, err := storage.User(ctx, normalized)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := storage.SetUserRoles(ctx, user.ID, roles); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("user %d (%s): %s\n", user.ID, normalized, strings.Join(roles, ", "))
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"os/signal"
 	"sso/internal/app"
 	"sso/internal/config"
 	"sso/internal/lib/logger/handlers/slogpretty"
+	"sso/internal/lib/logger/sl"
 	"syscall"
+	"time"
 )
 
 const (
@@ -16,20 +19,29 @@ const (
 	envProd  = "prod"
 )
 
+// shutdownTimeout is how long the REST server waits for requests in progress to finish on shutdown.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	cfg := config.MustLoad()
 
 	log := setupLogger(cfg.Env)
 
-	grpcApplication := app.NewGrpc(log, cfg.GRPC.Port, cfg.StoragePath, cfg.TokenTTL)
-	restApplication := app.NewRest(log, cfg.REST.Port, cfg.StoragePath, cfg.TokenTTL)
+	application := app.New(log, cfg)
 
 	go func() {
-		grpcApplication.GRPCServer.MustRun()
+		application.GRPCServer.MustRun()
 	}()
 
 	go func() {
-		restApplication.RestServer.MustRun()
+		application.RestServer.MustRun()
+	}()
+
+	purgerCtx, stopPurger := context.WithCancel(context.Background())
+	purgerDone := make(chan struct{})
+	go func() {
+		defer close(purgerDone)
+		application.Purger.Run(purgerCtx)
 	}()
 
 	// Graceful shutdown
@@ -39,7 +51,19 @@ func main() {
 
 	<-stop
 
-	grpcApplication.GRPCServer.Stop()
+	stopPurger()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := application.RestServer.Stop(ctx); err != nil {
+		log.Error("failed to stop HTTP server gracefully", sl.Err(err))
+	}
+	application.GRPCServer.Stop()
+
+	// A purge in progress is interrupted by the cancelled context, so this doesn't take long.
+	<-purgerDone
+
 	log.Info("Gracefully stopped")
 }
 
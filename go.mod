@@ -36,3 +36,6 @@ require (
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	olympos.io/encoding/edn v0.0.0-20201019073823-d3554ca0b0a3 // indirect
 )
+
+// The generated code of the API lives in this repository, next to the proto it is generated from.
+replace github.com/DenisPopkov/protos => ./protos
@@ -1,60 +1,237 @@
 package app
 
 import (
+	"encoding/base64"
 	"log/slog"
 	grpcapp "sso/internal/app/grpc"
 	restapp "sso/internal/app/rest"
+	"sso/internal/config"
+	"sso/internal/lib/password"
+	"sso/internal/lib/secret"
+	"sso/internal/lib/sms"
+	"sso/internal/services/apikeys"
+	"sso/internal/services/audit"
 	"sso/internal/services/auth"
+	"sso/internal/services/content"
 	"sso/internal/services/core"
+	"sso/internal/services/favorites"
+	"sso/internal/services/keys"
+	"sso/internal/services/oidc"
+	"sso/internal/services/purger"
 	"sso/internal/storage/sqlite"
-	"time"
 )
 
+// envProd is the environment of the production config.
+const envProd = "prod"
+
 type App struct {
 	log        *slog.Logger
 	GRPCServer *grpcapp.App
 	RestServer *restapp.App
-	port       int
+	Purger     *purger.Purger
 }
 
-func NewGrpc(
+// New builds the gRPC and REST servers on top of the same storage and services,
+// so that both sign tokens with the same keys and share caches.
+func New(
 	log *slog.Logger,
-	port int,
-	storagePath string,
-	tokenTTL time.Duration,
+	cfg *config.Config,
 ) *App {
-	storage, err := sqlite.New(storagePath)
+	storage, err := sqlite.New(cfg.StoragePath)
 	if err != nil {
 		panic(err)
 	}
 
-	authService := auth.New(log, storage, storage, storage, tokenTTL)
-	grpcApp := grpcapp.New(log, authService, port)
+	keysService := newKeys(log, storage, cfg)
+	auditService := audit.New(log, storage, cfg.Audit.Retention)
+	authService := newAuth(log, storage, keysService, auditService, cfg)
+	apiKeysService := apikeys.New(log, storage, storage)
+	grpcApp := grpcapp.New(log, authService, apiKeysService, cfg.GRPC.Port)
+	coreService := core.New(
+		log,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		authService,
+		authService,
+		auditService,
+		cfg.AccountDeletion.GracePeriod,
+	)
+	purgerService := purger.New(
+		log,
+		storage,
+		authService,
+		auditService,
+		cfg.AccountDeletion.GracePeriod,
+		cfg.AccountDeletion.PurgeInterval,
+	)
+	oidcService := oidc.New(log, authService, storage, storage, storage, storage, keysService, oidc.Settings{
+		Issuer:           cfg.OIDC.Issuer,
+		CodeTTL:          cfg.OIDC.CodeTTL,
+		TokenTTL:         cfg.TokenTTL,
+		SigningAlgorithm: cfg.SigningKeys.Algorithm,
+	})
+	restApp := restapp.New(
+		log,
+		coreService,
+		authService,
+		authService,
+		auditService,
+		oidcService,
+		content.New(log, storage),
+		favorites.New(log, storage),
+		keysService,
+		apiKeysService,
+		cfg.REST.Port,
+	)
 
 	return &App{
 		log:        log,
 		GRPCServer: grpcApp,
-		port:       port,
+		RestServer: restApp,
+		Purger:     purgerService,
+	}
+}
+
+func newKeys(log *slog.Logger, storage *sqlite.Storage, cfg *config.Config) *keys.Keys {
+	var cipher *secret.Cipher
+	if cfg.SigningKeys.EncryptionKey != "" {
+		key, err := base64.StdEncoding.DecodeString(cfg.SigningKeys.EncryptionKey)
+		if err != nil {
+			panic("invalid signing keys encryption key: " + err.Error())
+		}
+
+		cipher, err = secret.NewCipher(key)
+		if err != nil {
+			panic("invalid signing keys encryption key: " + err.Error())
+		}
+	} else {
+		log.Warn("signing keys encryption key is not set, private keys are stored unencrypted")
 	}
+
+	return keys.New(
+		log,
+		storage,
+		storage,
+		cfg.SigningKeys.Algorithm,
+		cfg.SigningKeys.RotationPeriod,
+		cfg.TokenTTL,
+		cipher,
+	)
 }
 
-func NewRest(
+func newAuth(
 	log *slog.Logger,
-	port int,
-	storagePath string,
-	tokenTTL time.Duration,
-) *App {
-	storage, err := sqlite.New(storagePath)
-	if err != nil {
-		panic(err)
+	storage *sqlite.Storage,
+	keysService *keys.Keys,
+	auditService *audit.Audit,
+	cfg *config.Config,
+) *auth.Auth {
+	return auth.New(
+		log,
+		auth.Deps{
+			UserSaver:     storage,
+			UserProvider:  storage,
+			AppProvider:   storage,
+			TokenStorage:  storage,
+			TokenRevoker:  storage,
+			KeyProvider:   keysService,
+			OTPStorage:    storage,
+			SMSSender:     newSMSSender(log, cfg.Env, cfg.SMS),
+			LoginAttempts: storage,
+			PasswordHasher: password.NewHasher(password.Params{
+				Memory:      cfg.PasswordHash.Memory,
+				Iterations:  cfg.PasswordHash.Iterations,
+				Parallelism: cfg.PasswordHash.Parallelism,
+			}),
+			TwoFactorStorage: storage,
+			SessionStorage:   storage,
+			RoleStorage:      storage,
+			GuestStorage:     storage,
+			Auditor:          auditService,
+		},
+		auth.Settings{
+			OTP: auth.OTPSettings{
+				Length:         cfg.OTP.Length,
+				TTL:            cfg.OTP.TTL,
+				MaxAttempts:    cfg.OTP.MaxAttempts,
+				ResendCooldown: cfg.OTP.ResendCooldown,
+			},
+			LoginThrottle: auth.LoginThrottleSettings{
+				Phone:        throttleSettings(cfg.LoginThrottle.Phone),
+				IP:           throttleSettings(cfg.LoginThrottle.IP),
+				CodeRequests: throttleSettings(cfg.LoginThrottle.CodeRequests),
+			},
+			PasswordPolicy: newPasswordPolicy(cfg.PasswordPolicy),
+			TwoFactor: auth.TwoFactorSettings{
+				Issuer:        cfg.TwoFactor.Issuer,
+				ChallengeTTL:  cfg.TwoFactor.ChallengeTTL,
+				MaxAttempts:   cfg.TwoFactor.MaxAttempts,
+				RecoveryCodes: cfg.TwoFactor.RecoveryCodes,
+			},
+			Introspection: auth.IntrospectionSettings{
+				CacheTTL:  cfg.Introspection.CacheTTL,
+				CacheSize: cfg.Introspection.CacheSize,
+			},
+			TokenIssuer:     cfg.TokenIssuer,
+			TokenTTL:        cfg.TokenTTL,
+			RefreshTokenTTL: cfg.RefreshTokenTTL,
+		},
+	)
+}
+
+func throttleSettings(cfg config.ThrottleConfig) auth.ThrottleSettings {
+	return auth.ThrottleSettings{
+		FreeAttempts:     cfg.FreeAttempts,
+		BaseDelay:        cfg.BaseDelay,
+		MaxDelay:         cfg.MaxDelay,
+		LockoutThreshold: cfg.Lockout(),
+		LockoutDuration:  cfg.LockoutDuration,
+		ResetAfter:       cfg.ResetAfter,
 	}
+}
 
-	coreService := core.New(log, storage, storage, storage, storage, storage, storage, tokenTTL)
-	restApp := restapp.New(log, coreService, port)
+func newPasswordPolicy(cfg config.PasswordPolicyConfig) password.Policy {
+	policy := password.Policy{
+		MinLength:     cfg.MinLength,
+		RequireLower:  cfg.RequireLower,
+		RequireUpper:  cfg.RequireUpper,
+		RequireDigit:  cfg.RequireDigit,
+		RequireSymbol: cfg.RequireSymbol,
+	}
 
-	return &App{
-		log:        log,
-		RestServer: restApp,
-		port:       port,
+	if cfg.DenylistPath != "" {
+		denylist, err := password.LoadDenylist(cfg.DenylistPath)
+		if err != nil {
+			panic(err)
+		}
+		policy.Denylist = denylist
+	}
+
+	return policy
+}
+
+// newSMSSender returns the sender of the config. The log and file senders don't deliver codes to users,
+// and the file one keeps them on disk, so they are refused in prod.
+func newSMSSender(log *slog.Logger, env string, cfg config.SMSConfig) auth.SMSSender {
+	if env == envProd && cfg.Sender != "http" {
+		panic("sms sender " + cfg.Sender + " is for local development and tests only, use http in prod")
+	}
+
+	switch cfg.Sender {
+	case "http":
+		if cfg.URL == "" {
+			panic("sms url is required by the http sender")
+		}
+		return sms.NewHTTPSender(cfg.URL, cfg.APIToken, cfg.Timeout)
+	case "log":
+		return sms.NewLogSender(log)
+	case "file":
+		return sms.NewFileSender(cfg.FilePath)
+	default:
+		panic("unknown sms sender: " + cfg.Sender)
 	}
 }
@@ -23,14 +23,13 @@ type App struct {
 func New(
 	log *slog.Logger,
 	authService authgrpc.Auth,
+	apiKeysService authgrpc.APIKeys,
 	port int,
 ) *App {
+	// Payloads are not logged: requests and responses carry passwords, one-time codes, tokens,
+	// TOTP secrets and recovery codes.
 	loggingOpts := []logging.Option{
-		logging.WithLogOnEvents(
-			//logging.StartCall, logging.FinishCall,
-			logging.PayloadReceived, logging.PayloadSent,
-		),
-		// Add any other option (check functions starting with logging.With).
+		logging.WithLogOnEvents(logging.StartCall, logging.FinishCall),
 	}
 
 	recoveryOpts := []recovery.Option{
@@ -44,6 +43,7 @@ func New(
 	gRPCServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
 		recovery.UnaryServerInterceptor(recoveryOpts...),
 		logging.UnaryServerInterceptor(InterceptorLogger(log), loggingOpts...),
+		authgrpc.UnaryServerInterceptor(authService, apiKeysService),
 	))
 
 	authgrpc.Register(gRPCServer, authService)
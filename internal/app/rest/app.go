@@ -4,30 +4,65 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	"log/slog"
 	"net/http"
+	"slices"
+	"sso/internal/domain/models"
+	adminhttp "sso/internal/http/admin"
+	authhttp "sso/internal/http/auth"
+	contenthttp "sso/internal/http/content"
+	favoriteshttp "sso/internal/http/favorites"
+	oidchttp "sso/internal/http/oidc"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/services/apikeys"
+	"sso/internal/services/auth"
 	"sso/internal/services/core"
+	"sso/internal/services/keys"
 	"strings"
 )
 
 type App struct {
-	log         *slog.Logger
-	httpServer  *http.Server
-	port        int
-	coreService *core.Core
+	log              *slog.Logger
+	httpServer       *http.Server
+	port             int
+	coreService      *core.Core
+	keysService      *keys.Keys
+	apiKeys          *apikeys.APIKeys
+	authService      authhttp.Auth
+	authHandler      *authhttp.Handler
+	adminHandler     *adminhttp.Handler
+	oidcHandler      *oidchttp.Handler
+	contentHandler   *contenthttp.Handler
+	favoritesHandler *favoriteshttp.Handler
 }
 
 func New(
 	log *slog.Logger,
 	coreService *core.Core,
+	authService authhttp.Auth,
+	adminService adminhttp.Admin,
+	auditService adminhttp.Audit,
+	oidcService oidchttp.OIDC,
+	contentService contenthttp.Content,
+	favoritesService favoriteshttp.Favorites,
+	keysService *keys.Keys,
+	apiKeysService *apikeys.APIKeys,
 	port int,
 ) *App {
 	return &App{
-		log:         log,
-		port:        port,
-		coreService: coreService,
+		log:              log,
+		httpServer:       &http.Server{Addr: fmt.Sprintf(":%d", port)},
+		port:             port,
+		coreService:      coreService,
+		keysService:      keysService,
+		apiKeys:          apiKeysService,
+		authService:      authService,
+		authHandler:      authhttp.New(log, authService),
+		adminHandler:     adminhttp.New(log, adminService, auditService, apiKeysService),
+		oidcHandler:      oidchttp.New(log, oidcService),
+		contentHandler:   contenthttp.New(log, contentService),
+		favoritesHandler: favoriteshttp.New(log, favoritesService),
 	}
 }
 
@@ -40,7 +75,10 @@ func (a *App) MustRun() {
 
 type MiddlewareFunc func(http.Handler) http.Handler
 
-// AuthMiddleware middleware function for JWT token validation and UID extraction
+// AuthMiddleware middleware function for JWT token validation and UID extraction.
+// Tokens are verified with the published signing keys against the app they were issued for,
+// revoked tokens and tokens of revoked sessions are rejected.
+// Services authenticate with API keys instead: such requests carry the scopes of the key and no UID.
 func (a *App) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -51,34 +89,80 @@ func (a *App) AuthMiddleware(next http.Handler) http.Handler {
 
 		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte("test-secret"), nil
-		})
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to parse JWT token: %v", err), http.StatusUnauthorized)
-			return
-		}
+		if apikeys.IsAPIKey(tokenString) {
+			key, err := a.apiKeys.Authenticate(r.Context(), tokenString)
+			if err != nil {
+				if errors.Is(err, apikeys.ErrInvalidAPIKey) {
+					http.Error(w, "Invalid API key", http.StatusUnauthorized)
+					return
+				}
+
+				a.log.Error("failed to authenticate api key", sl.Err(err))
+				http.Error(w, "Failed to validate API key", http.StatusInternalServerError)
+				return
+			}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			http.Error(w, "Invalid JWT token claims", http.StatusUnauthorized)
+			ctx := context.WithValue(r.Context(), "scopes", key.Scopes)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 
-		uidFloat64, ok := claims["uid"].(float64)
-		if !ok {
-			http.Error(w, "UID not found in JWT token claims", http.StatusUnauthorized)
+		claims, err := a.authService.ValidateToken(r.Context(), tokenString)
+		if err != nil {
+			switch {
+			case errors.Is(err, auth.ErrTokenRevoked):
+				http.Error(w, "JWT token is revoked", http.StatusUnauthorized)
+			case errors.Is(err, auth.ErrSessionRevoked):
+				http.Error(w, "Session is revoked", http.StatusUnauthorized)
+			case errors.Is(err, auth.ErrInvalidToken):
+				http.Error(w, fmt.Sprintf("Failed to parse JWT token: %v", err), http.StatusUnauthorized)
+			default:
+				a.log.Error("failed to validate token", sl.Err(err))
+				http.Error(w, "Failed to validate JWT token", http.StatusInternalServerError)
+			}
 			return
 		}
 
-		uid := int64(uidFloat64)
-
-		ctx := context.WithValue(r.Context(), "uid", uid)
+		ctx := context.WithValue(r.Context(), "uid", claims.UID)
+		ctx = context.WithValue(ctx, "token", tokenString)
+		ctx = context.WithValue(ctx, "sid", claims.SID)
+		ctx = context.WithValue(ctx, "roles", claims.Roles)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequirePermission returns middleware that lets through only requests whose token roles grant the permission,
+// or whose API key has it among its scopes. It must run after AuthMiddleware.
+func (a *App) RequirePermission(permission string) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			roles, _ := r.Context().Value("roles").([]string)
+			scopes, _ := r.Context().Value("scopes").([]string)
+			if !models.HasPermission(roles, permission) && !slices.Contains(scopes, permission) {
+				http.Error(w, "Permission denied", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireUser middleware lets through only requests made with a user token, not with an API key.
+// It must run after AuthMiddleware.
+func (a *App) RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value("uid").(int64); !ok {
+			http.Error(w, "User token is required", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // Run runs the HTTP server.
 func (a *App) Run() error {
 	const op = "restapp.Run"
@@ -89,26 +173,96 @@ func (a *App) Run() error {
 		return a.AuthMiddleware(next)
 	}
 
+	router.HandleFunc("/.well-known/jwks.json", a.keysService.JWKSHandler).Methods("GET")
+	router.HandleFunc("/auth/register", a.authHandler.Register).Methods("POST")
+	router.HandleFunc("/auth/login", a.authHandler.Login).Methods("POST")
+	router.HandleFunc("/.well-known/openid-configuration", a.oidcHandler.Discovery).Methods("GET")
+	router.HandleFunc("/oauth/authorize", a.oidcHandler.Authorize).Methods("GET", "POST")
+	router.HandleFunc("/oauth/token", a.oidcHandler.Token).Methods("POST")
+	router.HandleFunc("/oauth/userinfo", a.oidcHandler.UserInfo).Methods("GET", "POST")
+
 	authRouter := router.PathPrefix("").Subrouter()
 	authRouter.Use(authMiddleware)
 
-	authRouter.HandleFunc("/poets", a.coreService.UpdatePoetIsFaveHandler).Methods("PATCH")
-	authRouter.HandleFunc("/articles", a.coreService.UpdateArticleIsFaveHandler).Methods("PATCH")
-	authRouter.HandleFunc("/authors", a.coreService.UpdateAuthorIsFaveHandler).Methods("PATCH")
-	authRouter.HandleFunc("/user", a.coreService.DeleteUserHandler).Methods("DELETE")
-	authRouter.HandleFunc("/user", a.coreService.GetUserHandler).Methods("GET")
-	authRouter.HandleFunc("/quiz", a.coreService.GetQuizHandler).Methods("GET")
-	authRouter.HandleFunc("/clip", a.coreService.GetClipHandler).Methods("GET")
-	authRouter.HandleFunc("/articles", a.coreService.GetArticlesHandler).Methods("GET")
-	authRouter.HandleFunc("/poets", a.coreService.GetPoetsHandler).Methods("GET")
-	authRouter.HandleFunc("/authors", a.coreService.GetAuthorHandler).Methods("GET")
-
-	a.httpServer = &http.Server{
-		Addr:    fmt.Sprintf(":%d", a.port),
-		Handler: router,
+	userRouter := authRouter.PathPrefix("").Subrouter()
+	userRouter.Use(a.RequireUser)
+
+	userRouter.HandleFunc("/logout", a.authHandler.Logout).Methods("POST")
+	userRouter.HandleFunc("/password", a.authHandler.ChangePassword).Methods("POST")
+	userRouter.HandleFunc("/2fa/totp", a.authHandler.EnrollTOTP).Methods("POST")
+	userRouter.HandleFunc("/2fa/totp/confirm", a.authHandler.ConfirmTOTP).Methods("POST")
+	userRouter.HandleFunc("/2fa/totp", a.authHandler.DisableTOTP).Methods("DELETE")
+	userRouter.HandleFunc("/sessions", a.authHandler.Sessions).Methods("GET")
+	userRouter.HandleFunc("/sessions/{id}", a.authHandler.RevokeSession).Methods("DELETE")
+	userRouter.HandleFunc("/favorites", a.favoritesHandler.List).Methods("GET")
+	userRouter.HandleFunc("/favorites/{type}/{id}", a.favoritesHandler.Add).Methods("PUT")
+	userRouter.HandleFunc("/favorites/{type}/{id}", a.favoritesHandler.Remove).Methods("DELETE")
+	userRouter.HandleFunc("/user", a.coreService.DeleteUserHandler).Methods("DELETE")
+	userRouter.HandleFunc("/user/restore", a.coreService.RestoreUserHandler).Methods("POST")
+	userRouter.HandleFunc("/user", a.coreService.GetUserHandler).Methods("GET")
+	userRouter.HandleFunc("/quiz", a.coreService.GetQuizHandler).Methods("GET")
+	userRouter.HandleFunc("/clip", a.coreService.GetClipHandler).Methods("GET")
+	userRouter.HandleFunc("/articles", a.coreService.GetArticlesHandler).Methods("GET")
+	userRouter.HandleFunc("/poets", a.coreService.GetPoetsHandler).Methods("GET")
+	userRouter.HandleFunc("/authors", a.coreService.GetAuthorHandler).Methods("GET")
+
+	usersRouter := authRouter.PathPrefix("/admin/users").Subrouter()
+	usersRouter.Use(mux.MiddlewareFunc(a.RequirePermission(models.PermissionManageUsers)))
+
+	usersRouter.HandleFunc("/{id}/roles", a.adminHandler.UserRoles).Methods("GET")
+	usersRouter.HandleFunc("/{id}/roles", a.adminHandler.SetUserRoles).Methods("PUT")
+	usersRouter.HandleFunc("/{id}/two-factor", a.adminHandler.SetTwoFactorRequired).Methods("PUT")
+
+	auditRouter := authRouter.PathPrefix("/admin/audit").Subrouter()
+	auditRouter.Use(mux.MiddlewareFunc(a.RequirePermission(models.PermissionViewAudit)))
+
+	auditRouter.HandleFunc("", a.adminHandler.AuditEvents).Methods("GET")
+
+	apiKeysRouter := authRouter.PathPrefix("/admin/api-keys").Subrouter()
+	apiKeysRouter.Use(mux.MiddlewareFunc(a.RequirePermission(models.PermissionManageAPIKeys)))
+
+	apiKeysRouter.HandleFunc("", a.adminHandler.CreateAPIKey).Methods("POST")
+	apiKeysRouter.HandleFunc("", a.adminHandler.APIKeys).Methods("GET")
+	apiKeysRouter.HandleFunc("/{id}", a.adminHandler.RevokeAPIKey).Methods("DELETE")
+
+	// Registered after the other admin routers, so that it doesn't shadow them.
+	contentRouter := authRouter.PathPrefix("/admin").Subrouter()
+	contentRouter.Use(mux.MiddlewareFunc(a.RequirePermission(models.PermissionEditContent)))
+
+	contentRouter.HandleFunc("/authors", a.contentHandler.CreateAuthor).Methods("POST")
+	contentRouter.HandleFunc("/authors/{id}", a.contentHandler.UpdateAuthor).Methods("PUT")
+	contentRouter.HandleFunc("/authors/{id}", a.contentHandler.DeleteAuthor).Methods("DELETE")
+	contentRouter.HandleFunc("/poets", a.contentHandler.CreatePoet).Methods("POST")
+	contentRouter.HandleFunc("/poets/{id}", a.contentHandler.UpdatePoet).Methods("PUT")
+	contentRouter.HandleFunc("/poets/{id}", a.contentHandler.DeletePoet).Methods("DELETE")
+	contentRouter.HandleFunc("/articles", a.contentHandler.CreateArticle).Methods("POST")
+	contentRouter.HandleFunc("/articles/{id}", a.contentHandler.UpdateArticle).Methods("PUT")
+	contentRouter.HandleFunc("/articles/{id}", a.contentHandler.DeleteArticle).Methods("DELETE")
+	contentRouter.HandleFunc("/clips", a.contentHandler.CreateClip).Methods("POST")
+	contentRouter.HandleFunc("/clips/{id}", a.contentHandler.UpdateClip).Methods("PUT")
+	contentRouter.HandleFunc("/clips/{id}", a.contentHandler.DeleteClip).Methods("DELETE")
+	contentRouter.HandleFunc("/quizzes", a.contentHandler.CreateQuiz).Methods("POST")
+	contentRouter.HandleFunc("/quizzes/{id}", a.contentHandler.UpdateQuiz).Methods("PUT")
+	contentRouter.HandleFunc("/quizzes/{id}", a.contentHandler.DeleteQuiz).Methods("DELETE")
+
+	// The server itself is made in New, so that Stop works even if it is called before Run gets here.
+	a.httpServer.Handler = router
+
+	if err := a.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := a.httpServer.ListenAndServe(); err != nil && errors.Is(err, http.ErrServerClosed) {
+	return nil
+}
+
+// Stop stops the HTTP server, waiting for requests in progress until the context is done.
+func (a *App) Stop(ctx context.Context) error {
+	const op = "restapp.Stop"
+
+	a.log.With(slog.String("op", op)).
+		Info("stopping HTTP server", slog.Int("port", a.port))
+
+	if err := a.httpServer.Shutdown(ctx); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
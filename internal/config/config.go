@@ -8,12 +8,25 @@ import (
 )
 
 type Config struct {
-	Env            string     `yaml:"env" env-default:"local"`
-	StoragePath    string     `yaml:"storage_path" env-required:"true"`
-	GRPC           GRPCConfig `yaml:"grpc"`
-	REST           RESTConfig `yaml:"rest"`
-	MigrationsPath string
-	TokenTTL       time.Duration `yaml:"token_ttl" env-default:"720h"`
+	Env             string     `yaml:"env" env-default:"local"`
+	StoragePath     string     `yaml:"storage_path" env-required:"true"`
+	GRPC            GRPCConfig `yaml:"grpc"`
+	REST            RESTConfig `yaml:"rest"`
+	MigrationsPath  string
+	TokenIssuer     string                `yaml:"token_issuer" env-default:"sso"`
+	TokenTTL        time.Duration         `yaml:"token_ttl" env-default:"15m"`
+	RefreshTokenTTL time.Duration         `yaml:"refresh_token_ttl" env-default:"720h"`
+	SigningKeys     SigningKeysConfig     `yaml:"signing_keys"`
+	OTP             OTPConfig             `yaml:"otp"`
+	SMS             SMSConfig             `yaml:"sms"`
+	LoginThrottle   LoginThrottleConfig   `yaml:"login_throttle"`
+	PasswordPolicy  PasswordPolicyConfig  `yaml:"password_policy"`
+	PasswordHash    PasswordHashConfig    `yaml:"password_hash"`
+	TwoFactor       TwoFactorConfig       `yaml:"two_factor"`
+	Audit           AuditConfig           `yaml:"audit"`
+	OIDC            OIDCConfig            `yaml:"oidc"`
+	Introspection   IntrospectionConfig   `yaml:"introspection"`
+	AccountDeletion AccountDeletionConfig `yaml:"account_deletion"`
 }
 
 type GRPCConfig struct {
@@ -26,6 +39,117 @@ type RESTConfig struct {
 	Timeout time.Duration `yaml:"timeout"`
 }
 
+type SigningKeysConfig struct {
+	// Algorithm is either RS256 or EdDSA.
+	Algorithm      string        `yaml:"algorithm" env-default:"EdDSA"`
+	RotationPeriod time.Duration `yaml:"rotation_period" env-default:"720h"`
+	// EncryptionKey is the base64-encoded 32-byte key private keys are encrypted with in the storage.
+	// It is better passed in the environment than kept in the config file.
+	// If it is empty, private keys are stored unencrypted.
+	EncryptionKey string `yaml:"encryption_key" env:"SIGNING_KEYS_ENCRYPTION_KEY"`
+}
+
+type OTPConfig struct {
+	Length         int           `yaml:"length" env-default:"6"`
+	TTL            time.Duration `yaml:"ttl" env-default:"5m"`
+	MaxAttempts    int           `yaml:"max_attempts" env-default:"5"`
+	ResendCooldown time.Duration `yaml:"resend_cooldown" env-default:"1m"`
+}
+
+type SMSConfig struct {
+	// Sender is http, which sends messages through the provider at URL, or, for local development and tests only,
+	// log, which logs that a message was sent, or file, which appends messages to FilePath.
+	Sender   string        `yaml:"sender" env-default:"log"`
+	FilePath string        `yaml:"file_path"`
+	URL      string        `yaml:"url" env:"SMS_URL"`
+	APIToken string        `yaml:"api_token" env:"SMS_API_TOKEN"`
+	Timeout  time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+type LoginThrottleConfig struct {
+	Phone ThrottleConfig `yaml:"phone"`
+	IP    ThrottleConfig `yaml:"ip"`
+	// CodeRequests throttle login codes requested from one IP. Every request counts as an attempt.
+	CodeRequests ThrottleConfig `yaml:"code_requests"`
+}
+
+type ThrottleConfig struct {
+	// FreeAttempts is the number of failed logins in a row allowed without a delay.
+	FreeAttempts int `yaml:"free_attempts" env-default:"3"`
+	// BaseDelay doubles with every next failure up to MaxDelay.
+	BaseDelay time.Duration `yaml:"base_delay" env-default:"1s"`
+	MaxDelay  time.Duration `yaml:"max_delay" env-default:"5m"`
+	// LockoutThreshold is the number of failures after which logins are locked for LockoutDuration.
+	// If it is not set, DefaultLockoutThreshold is used, and zero disables the lockout. It is a pointer
+	// without env-default, since cleanenv would replace zero with the default.
+	LockoutThreshold *int          `yaml:"lockout_threshold"`
+	LockoutDuration  time.Duration `yaml:"lockout_duration" env-default:"30m"`
+	// ResetAfter is how long a failure is remembered.
+	ResetAfter time.Duration `yaml:"reset_after" env-default:"1h"`
+}
+
+// DefaultLockoutThreshold is the lockout threshold of throttles that don't set one.
+const DefaultLockoutThreshold = 10
+
+// Lockout returns the lockout threshold of the throttle, zero if the lockout is disabled.
+func (c ThrottleConfig) Lockout() int {
+	if c.LockoutThreshold == nil {
+		return DefaultLockoutThreshold
+	}
+
+	return *c.LockoutThreshold
+}
+
+type PasswordPolicyConfig struct {
+	MinLength     int  `yaml:"min_length" env-default:"8"`
+	RequireLower  bool `yaml:"require_lower"`
+	RequireUpper  bool `yaml:"require_upper"`
+	RequireDigit  bool `yaml:"require_digit"`
+	RequireSymbol bool `yaml:"require_symbol"`
+	// DenylistPath is a file with common passwords, one per line, that are not allowed.
+	DenylistPath string `yaml:"denylist_path"`
+}
+
+// PasswordHashConfig holds argon2id parameters. Hashes made with other parameters are replaced on login.
+type PasswordHashConfig struct {
+	// Memory is in KiB.
+	Memory      uint32 `yaml:"memory" env-default:"65536"`
+	Iterations  uint32 `yaml:"iterations" env-default:"3"`
+	Parallelism uint8  `yaml:"parallelism" env-default:"2"`
+}
+
+type TwoFactorConfig struct {
+	// Issuer is the name authenticator apps show for the account.
+	Issuer        string        `yaml:"issuer" env-default:"RusseLiterature"`
+	ChallengeTTL  time.Duration `yaml:"challenge_ttl" env-default:"5m"`
+	MaxAttempts   int           `yaml:"max_attempts" env-default:"5"`
+	RecoveryCodes int           `yaml:"recovery_codes" env-default:"10"`
+}
+
+type AuditConfig struct {
+	// Retention is how long audit events are kept. Zero keeps them forever.
+	Retention time.Duration `yaml:"retention" env-default:"2160h"`
+}
+
+type OIDCConfig struct {
+	// Issuer is the public base URL of the REST server. It is the iss claim of ID tokens.
+	Issuer  string        `yaml:"issuer" env-default:"http://localhost:4041"`
+	CodeTTL time.Duration `yaml:"code_ttl" env-default:"1m"`
+}
+
+type IntrospectionConfig struct {
+	// CacheTTL is how long results of ValidateToken and GetUser are cached. Zero disables caching.
+	CacheTTL  time.Duration `yaml:"cache_ttl" env-default:"10s"`
+	CacheSize int           `yaml:"cache_size" env-default:"10000"`
+}
+
+type AccountDeletionConfig struct {
+	// GracePeriod is how long a deleted account can be restored before all of its data is deleted.
+	GracePeriod time.Duration `yaml:"grace_period" env-default:"720h"`
+	// PurgeInterval is how often accounts whose grace period is over are looked for.
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+}
+
 func MustLoad() *Config {
 	configPath := fetchConfigPath()
 	if configPath == "" {
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// APIKey lets a service of an app call the API without a user. The key grants only its scopes,
+// which are permissions, see IsAPIKeyScope.
+type APIKey struct {
+	ID    int64  `json:"id"`
+	AppID int64  `json:"appId"`
+	Name  string `json:"name"`
+	// Prefix is the beginning of the key, shown to tell keys apart. The key itself is stored only as a hash.
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
@@ -1,6 +1,11 @@
 package models
 
+import "time"
+
 type App struct {
+	ID     int64
 	Name   string
 	Secret string
+	// TokenTTL overrides the default access token lifetime if positive.
+	TokenTTL time.Duration
 }
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+const (
+	AuditEventLogin        = "login"
+	AuditEventLoginCode    = "login_code"
+	AuditEventTwoFactor    = "two_factor"
+	AuditEventRegister     = "register"
+	AuditEventUserDelete   = "user_delete"
+	AuditEventUserRestore  = "user_restore"
+	AuditEventUserPurge    = "user_purge"
+	AuditEventGuestUpgrade = "guest_upgrade"
+)
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEvent is a persisted record of an authentication event.
+type AuditEvent struct {
+	ID     int64     `json:"id"`
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"`
+	UserID int64     `json:"userId,omitempty"`
+	// Phone is the phone the event is about. Only its hash is stored.
+	Phone     string `json:"-"`
+	PhoneHash string `json:"phoneHash,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"userAgent,omitempty"`
+	Outcome   string `json:"outcome"`
+	// Reason explains the outcome, e.g. why a login failed.
+	Reason string `json:"reason,omitempty"`
+}
+
+// AuditFilter selects audit events. Zero fields match any event.
+type AuditFilter struct {
+	Event   string
+	Outcome string
+	UserID  int64
+	// Phone is resolved to PhoneHash before the events are queried.
+	Phone     string
+	PhoneHash string
+	IP        string
+	From      time.Time
+	To        time.Time
+	// Before is the ID of the last event of the previous page: only older events are returned.
+	Before int64
+	Limit  int
+}
@@ -0,0 +1,23 @@
+package models
+
+// CatalogFilter selects a page of authors, poets or articles as a user sees them.
+type CatalogFilter struct {
+	// SortByName sorts the entries by name and then by ID instead of only by ID.
+	SortByName bool
+	Descending bool
+	// IsFave, if set, selects only the entries that are or aren't in favorites of the user.
+	IsFave *bool
+	// After is the position of the last entry of the previous page: only the entries after it are returned.
+	After *CatalogPosition
+	// Limit is the page size. Zero means the whole catalog.
+	Limit int
+}
+
+// CatalogPosition is the place of an entry in a sorted catalog. Name is set only when sorting by name.
+// SortByName and Descending tell the order the position was taken in: it means nothing in another order.
+type CatalogPosition struct {
+	SortByName bool   `json:"byName,omitempty"`
+	Descending bool   `json:"desc,omitempty"`
+	Name       string `json:"name,omitempty"`
+	ID         int64  `json:"id"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// ClientInfo describes the client a request came from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	// DeviceName and Platform are reported by the client app, e.g. "Pixel 8" and "android".
+	DeviceName string
+	Platform   string
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Types of content a user can keep in favorites.
+const (
+	FavoriteTypeAuthor  = "author"
+	FavoriteTypePoet    = "poet"
+	FavoriteTypeArticle = "article"
+)
+
+// FavoriteTypes lists the types of content a user can keep in favorites.
+var FavoriteTypes = []string{FavoriteTypeAuthor, FavoriteTypePoet, FavoriteTypeArticle}
+
+var favoriteTypes = map[string]bool{
+	FavoriteTypeAuthor:  true,
+	FavoriteTypePoet:    true,
+	FavoriteTypeArticle: true,
+}
+
+// IsFavoriteType reports whether content of the type can be kept in favorites.
+func IsFavoriteType(contentType string) bool {
+	return favoriteTypes[contentType]
+}
+
+// Favorite is the state of a content item in favorites of a user.
+type Favorite struct {
+	Type   string `json:"type"`
+	ID     int64  `json:"id"`
+	IsFave bool   `json:"isFave"`
+	// FavoritedAt, Name, Image and Clip are set in lists of favorites.
+	FavoritedAt *time.Time `json:"favoritedAt,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	Image       string     `json:"image,omitempty"`
+	Clip        int64      `json:"clip,omitempty"`
+}
+
+// FavoritePosition is the place of a favorite in a list of favorites sorted by the time they were added.
+// Favorites added in the same second are sorted by type, in the order of FavoriteTypes, and by ID.
+// Ascending tells the order the position was taken in: it means nothing in the other order.
+type FavoritePosition struct {
+	Ascending   bool   `json:"asc,omitempty"`
+	FavoritedAt int64  `json:"t"`
+	Type        string `json:"type"`
+	ID          int64  `json:"id"`
+}
+
+// FavoriteFilter selects favorites of a user. Zero fields match any favorite.
+type FavoriteFilter struct {
+	Type string
+	// Ascending lists the oldest favorites first instead of the recent ones.
+	Ascending bool
+	// After is the position of the last favorite of the previous page: only the favorites after it are returned.
+	After *FavoritePosition
+	Limit int
+}
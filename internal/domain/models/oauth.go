@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+const (
+	OAuthScopeOpenID  = "openid"
+	OAuthScopeProfile = "profile"
+	OAuthScopePhone   = "phone"
+)
+
+// OAuthClient is a client registered to sign users in with OpenID Connect. It gets tokens of its app.
+type OAuthClient struct {
+	ID    string
+	AppID int64
+	Name  string
+	// SecretHash is empty for public clients, such as single-page and mobile apps,
+	// which can't keep a secret and are verified with PKCE only.
+	SecretHash   string
+	RedirectURIs []string
+}
+
+// AuthorizeRequest is an OAuth 2.0 authorization request with PKCE.
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// AuthCode is an authorization code issued to an OAuth client.
+type AuthCode struct {
+	CodeHash      string
+	ClientID      string
+	RedirectURI   string
+	UserID        int64
+	Scope         string
+	Nonce         string
+	CodeChallenge string
+	// Tokens are the tokens issued to the user on authorization, sealed with a key derived from the code.
+	Tokens    []byte
+	AuthTime  time.Time
+	ExpiresAt time.Time
+}
+
+// TokenRequest is a request to the OAuth 2.0 token endpoint.
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	ClientID     string
+	ClientSecret string
+}
+
+// OAuthTokens are the tokens returned by the OAuth 2.0 token endpoint.
+type OAuthTokens struct {
+	TokenPair
+	// IDToken is issued only for the authorization code grant.
+	IDToken   string
+	Scope     string
+	ExpiresIn time.Duration
+}
+
+// UserInfo are the OpenID Connect claims about the user.
+type UserInfo struct {
+	Subject     string `json:"sub"`
+	Name        string `json:"name,omitempty"`
+	Picture     string `json:"picture,omitempty"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+const (
+	OTPPurposeLogin         = "login"
+	OTPPurposePasswordReset = "password_reset"
+)
+
+type OTP struct {
+	Phone     string
+	Purpose   string
+	CodeHash  string
+	ExpiresAt time.Time
+	SentAt    time.Time
+	Attempts  int
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	AppID     int64
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	Used      bool
+	Revoked   bool
+}
@@ -0,0 +1,71 @@
+package models
+
+const (
+	RoleUser   = "user"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+const (
+	// PermissionEditContent allows to create, update and delete authors, articles, poets, clips and quizzes.
+	PermissionEditContent = "content:edit"
+	// PermissionManageUsers allows to change roles and security settings of other users.
+	PermissionManageUsers = "users:manage"
+	// PermissionViewAudit allows to query the authentication audit log.
+	PermissionViewAudit = "audit:view"
+	// PermissionReadUsers allows to look up users and their roles by ID.
+	PermissionReadUsers = "users:read"
+	// PermissionValidateTokens allows to check access tokens of users with the ValidateToken RPC.
+	PermissionValidateTokens = "tokens:validate"
+	// PermissionManageAPIKeys allows to create, list and revoke API keys.
+	PermissionManageAPIKeys = "api_keys:manage"
+)
+
+// rolePermissions lists what each role allows in addition to what every user can do.
+var rolePermissions = map[string][]string{
+	RoleUser:   {},
+	RoleEditor: {PermissionEditContent},
+	RoleAdmin: {
+		PermissionEditContent,
+		PermissionManageUsers,
+		PermissionViewAudit,
+		PermissionReadUsers,
+		PermissionValidateTokens,
+		PermissionManageAPIKeys,
+	},
+}
+
+// apiKeyScopes are the permissions API keys can be granted. API keys can't manage API keys,
+// so that a leaked key can't be used to create more.
+var apiKeyScopes = map[string]bool{
+	PermissionEditContent:    true,
+	PermissionManageUsers:    true,
+	PermissionViewAudit:      true,
+	PermissionReadUsers:      true,
+	PermissionValidateTokens: true,
+}
+
+// IsRole reports whether role is one of the known roles.
+func IsRole(role string) bool {
+	_, ok := rolePermissions[role]
+
+	return ok
+}
+
+// IsAPIKeyScope reports whether API keys can be granted the permission.
+func IsAPIKeyScope(scope string) bool {
+	return apiKeyScopes[scope]
+}
+
+// HasPermission reports whether any of the roles grants the permission.
+func HasPermission(roles []string, permission string) bool {
+	for _, role := range roles {
+		for _, p := range rolePermissions[role] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Session is a login of a user on a device. Its ID is the ID of the refresh token family issued on login,
+// and access tokens of the session carry it in the sid claim.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     int64     `json:"-"`
+	AppID      int64     `json:"appId"`
+	DeviceName string    `json:"deviceName"`
+	Platform   string    `json:"platform"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"-"`
+	Revoked    bool      `json:"-"`
+	// Current is set for the session of the token the sessions are listed with.
+	Current bool `json:"current"`
+}
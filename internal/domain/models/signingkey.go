@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey []byte
+	PublicKey  []byte
+	CreatedAt  time.Time
+	// ExpiresAt is set once the key is retired: after it no token signed with the key is valid.
+	ExpiresAt time.Time
+}
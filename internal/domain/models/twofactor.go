@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// TOTP is the authenticator app secret of a user. It is used for two-factor authentication once confirmed.
+type TOTP struct {
+	UserID    int64
+	Secret    string
+	Confirmed bool
+	// LastStep is the time step of the last accepted code. Codes of this and earlier steps are rejected.
+	LastStep int64
+}
+
+// TOTPEnrollment is what a user needs to add the secret to an authenticator app.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// TwoFactorChallenge is issued on login with a valid password when the second factor is needed.
+type TwoFactorChallenge struct {
+	Hash      string
+	UserID    int64
+	AppID     int64
+	ExpiresAt time.Time
+	Attempts  int
+}
+
+// LoginResult is either a pair of tokens or, if the second factor is needed, a challenge to exchange for them.
+type LoginResult struct {
+	TokenPair
+	TwoFactorChallenge string
+}
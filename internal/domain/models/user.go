@@ -1,9 +1,17 @@
 package models
 
+import "time"
+
 type User struct {
 	ID       int64  `json:"id"`
 	Phone    string `json:"phone"`
 	PassHash []byte `json:"passHash"`
 	Name     string `json:"name"`
 	Image    string `json:"image"`
+	// Guest users are bound to a device and have neither a phone nor a password.
+	Guest bool `json:"guest,omitempty"`
+	// Roles are loaded only when tokens are issued to the user.
+	Roles []string `json:"roles,omitempty"`
+	// DeletedAt is set while the account is pending deletion and can still be restored.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
 }
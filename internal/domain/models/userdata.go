@@ -1,6 +1,12 @@
 package models
 
+import "time"
+
 type UserData struct {
 	Name  string `json:"name"`
 	Image string `json:"image"`
+	// DeletedAt and PurgeAt are set while the account is pending deletion:
+	// it can be restored until PurgeAt, when it is deleted for good.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	PurgeAt   *time.Time `json:"purgeAt,omitempty"`
 }
@@ -0,0 +1,98 @@
+package authgrpc
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/DenisPopkov/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"slices"
+	"sso/internal/domain/models"
+	"sso/internal/services/apikeys"
+	"sso/internal/services/auth"
+	"strings"
+)
+
+type APIKeys interface {
+	Authenticate(ctx context.Context, credential string) (models.APIKey, error)
+}
+
+// methodPermissions lists the RPCs meant for other services and the permission their callers need.
+var methodPermissions = map[string]string{
+	ssov1.Auth_IsAdmin_FullMethodName:       models.PermissionReadUsers,
+	ssov1.Auth_GetRoles_FullMethodName:      models.PermissionReadUsers,
+	ssov1.Auth_GetUser_FullMethodName:       models.PermissionReadUsers,
+	ssov1.Auth_ValidateToken_FullMethodName: models.PermissionValidateTokens,
+}
+
+// UnaryServerInterceptor authenticates the callers of the RPCs meant for other services.
+// The caller passes an API key or a user access token in the authorization metadata as "Bearer <credential>",
+// and the scopes of the key or the roles of the user must grant the permission the RPC needs.
+// Other RPCs authenticate users by the tokens in their requests and are not checked.
+func UnaryServerInterceptor(authService Auth, apiKeys APIKeys) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		permission, ok := methodPermissions[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		credential := bearerCredential(ctx)
+		if credential == "" {
+			return nil, status.Error(codes.Unauthenticated, "authorization metadata is required")
+		}
+
+		if apikeys.IsAPIKey(credential) {
+			key, err := apiKeys.Authenticate(ctx, credential)
+			if err != nil {
+				if errors.Is(err, apikeys.ErrInvalidAPIKey) {
+					return nil, status.Error(codes.Unauthenticated, "invalid api key")
+				}
+
+				return nil, status.Error(codes.Internal, "failed to authenticate api key")
+			}
+
+			if !slices.Contains(key.Scopes, permission) {
+				return nil, status.Error(codes.PermissionDenied, "api key has no "+permission+" scope")
+			}
+
+			return handler(ctx, req)
+		}
+
+		claims, err := authService.ValidateToken(ctx, credential)
+		if err != nil {
+			if auth.InvalidTokenReason(err) != "" {
+				return nil, status.Error(codes.Unauthenticated, "invalid token")
+			}
+
+			return nil, status.Error(codes.Internal, "failed to validate token")
+		}
+
+		if !models.HasPermission(claims.Roles, permission) {
+			return nil, status.Error(codes.PermissionDenied, "permission denied")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// bearerCredential returns the credential from the "Bearer <credential>" authorization metadata.
+func bearerCredential(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ""
+	}
+
+	return strings.TrimPrefix(values[0], "Bearer ")
+}
@@ -4,11 +4,20 @@ import (
 	"context"
 	"errors"
 	ssov1 "github.com/DenisPopkov/protos/gen/go/sso"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"net"
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/password"
 	"sso/internal/services/auth"
 	"sso/internal/storage"
+	"time"
 )
 
 type Auth interface {
@@ -16,12 +25,89 @@ type Auth interface {
 		ctx context.Context,
 		phone string,
 		password string,
-	) (token string, err error)
+		appID int64,
+		client models.ClientInfo,
+	) (result models.LoginResult, err error)
+	Refresh(
+		ctx context.Context,
+		refreshToken string,
+		client models.ClientInfo,
+	) (tokens models.TokenPair, err error)
 	RegisterNewUser(
 		ctx context.Context,
 		phone string,
 		password string,
+		appID int64,
+		client models.ClientInfo,
 	) (userID int64, err error)
+	Logout(
+		ctx context.Context,
+		accessToken string,
+		refreshToken string,
+	) error
+	RequestCode(
+		ctx context.Context,
+		phone string,
+		client models.ClientInfo,
+	) error
+	VerifyCode(
+		ctx context.Context,
+		phone string,
+		code string,
+		appID int64,
+		client models.ClientInfo,
+	) (result models.LoginResult, err error)
+	ChangePassword(
+		ctx context.Context,
+		accessToken string,
+		oldPassword string,
+		newPassword string,
+	) error
+	RequestPasswordReset(
+		ctx context.Context,
+		phone string,
+	) error
+	ResetPassword(
+		ctx context.Context,
+		phone string,
+		code string,
+		newPassword string,
+	) error
+	VerifyTwoFactor(
+		ctx context.Context,
+		challenge string,
+		code string,
+		client models.ClientInfo,
+	) (tokens models.TokenPair, err error)
+	IsAdmin(
+		ctx context.Context,
+		userID int64,
+	) (isAdmin bool, err error)
+	Roles(
+		ctx context.Context,
+		userID int64,
+	) (roles []string, err error)
+	RegisterGuest(
+		ctx context.Context,
+		deviceID string,
+		appID int64,
+		client models.ClientInfo,
+	) (userID int64, tokens models.TokenPair, err error)
+	UpgradeGuest(
+		ctx context.Context,
+		accessToken string,
+		phone string,
+		password string,
+		client models.ClientInfo,
+	) error
+	ValidateToken(
+		ctx context.Context,
+		accessToken string,
+	) (claims jwt.Claims, err error)
+	User(
+		ctx context.Context,
+		userID int64,
+	) (user models.User, err error)
 }
 
 type serverAPI struct {
@@ -45,16 +131,64 @@ func (s *serverAPI) Login(
 		return nil, status.Error(codes.InvalidArgument, "password is required")
 	}
 
-	token, err := s.auth.Login(ctx, in.GetPhone(), in.GetPassword())
+	if in.AppId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	result, err := s.auth.Login(ctx, in.GetPhone(), in.GetPassword(), int64(in.GetAppId()), clientInfo(ctx))
 	if err != nil {
+		if errors.Is(err, auth.ErrInvalidPhone) {
+			return nil, status.Error(codes.InvalidArgument, "invalid phone")
+		}
+
 		if errors.Is(err, auth.ErrInvalidCredentials) {
 			return nil, status.Error(codes.InvalidArgument, "invalid phone or password")
 		}
 
+		if errors.Is(err, auth.ErrTwoFactorRequired) {
+			return nil, status.Error(codes.FailedPrecondition, "two-factor authentication must be enabled first")
+		}
+
+		var retryErr *auth.RetryAfterError
+		if errors.As(err, &retryErr) {
+			return nil, retryAfterError("too many login attempts", retryErr.RetryAfter)
+		}
+
+		if errors.Is(err, auth.ErrInvalidApp) {
+			return nil, status.Error(codes.InvalidArgument, "invalid app_id")
+		}
+
 		return nil, status.Error(codes.Internal, "failed to login")
 	}
 
-	return &ssov1.LoginResponse{Token: token}, nil
+	return &ssov1.LoginResponse{
+		Token:              result.AccessToken,
+		RefreshToken:       result.RefreshToken,
+		TwoFactorChallenge: result.TwoFactorChallenge,
+	}, nil
+}
+
+func (s *serverAPI) Refresh(
+	ctx context.Context,
+	in *ssov1.RefreshRequest,
+) (*ssov1.RefreshResponse, error) {
+	if in.RefreshToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh token is required")
+	}
+
+	tokens, err := s.auth.Refresh(ctx, in.GetRefreshToken(), clientInfo(ctx))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to refresh token")
+	}
+
+	return &ssov1.RefreshResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
 }
 
 func (s *serverAPI) Register(
@@ -69,14 +203,494 @@ func (s *serverAPI) Register(
 		return nil, status.Error(codes.InvalidArgument, "password is required")
 	}
 
-	uid, err := s.auth.RegisterNewUser(ctx, in.GetPhone(), in.GetPassword())
+	if in.AppId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	uid, err := s.auth.RegisterNewUser(ctx, in.GetPhone(), in.GetPassword(), int64(in.GetAppId()), clientInfo(ctx))
 	if err != nil {
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			return nil, status.Error(codes.InvalidArgument, policyErr.Error())
+		}
+
+		if errors.Is(err, auth.ErrInvalidPhone) {
+			return nil, status.Error(codes.InvalidArgument, "invalid phone")
+		}
+
 		if errors.Is(err, storage.ErrUserExists) {
 			return nil, status.Error(codes.AlreadyExists, "user already exists")
 		}
 
+		if errors.Is(err, auth.ErrInvalidApp) {
+			return nil, status.Error(codes.InvalidArgument, "invalid app_id")
+		}
+
 		return nil, status.Error(codes.Internal, "failed to register user")
 	}
 
 	return &ssov1.RegisterResponse{UserId: uid}, nil
 }
+
+func (s *serverAPI) Logout(
+	ctx context.Context,
+	in *ssov1.LogoutRequest,
+) (*ssov1.LogoutResponse, error) {
+	if in.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	err := s.auth.Logout(ctx, in.GetToken(), in.GetRefreshToken())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to logout")
+	}
+
+	return &ssov1.LogoutResponse{}, nil
+}
+
+func (s *serverAPI) RequestCode(
+	ctx context.Context,
+	in *ssov1.RequestCodeRequest,
+) (*ssov1.RequestCodeResponse, error) {
+	if in.Phone == "" {
+		return nil, status.Error(codes.InvalidArgument, "phone is required")
+	}
+
+	err := s.auth.RequestCode(ctx, in.GetPhone(), clientInfo(ctx))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidPhone) {
+			return nil, status.Error(codes.InvalidArgument, "invalid phone")
+		}
+
+		var retryErr *auth.RetryAfterError
+		if errors.As(err, &retryErr) {
+			if errors.Is(err, auth.ErrTooManyCodeRequests) {
+				return nil, retryAfterError("too many code requests", retryErr.RetryAfter)
+			}
+
+			return nil, retryAfterError("code was sent recently", retryErr.RetryAfter)
+		}
+
+		return nil, status.Error(codes.Internal, "failed to send code")
+	}
+
+	return &ssov1.RequestCodeResponse{}, nil
+}
+
+func (s *serverAPI) VerifyCode(
+	ctx context.Context,
+	in *ssov1.VerifyCodeRequest,
+) (*ssov1.VerifyCodeResponse, error) {
+	if in.Phone == "" {
+		return nil, status.Error(codes.InvalidArgument, "phone is required")
+	}
+
+	if in.Code == "" {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+
+	if in.AppId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	result, err := s.auth.VerifyCode(ctx, in.GetPhone(), in.GetCode(), int64(in.GetAppId()), clientInfo(ctx))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidPhone) {
+			return nil, status.Error(codes.InvalidArgument, "invalid phone")
+		}
+
+		if err := codeError(err); err != nil {
+			return nil, err
+		}
+
+		if errors.Is(err, auth.ErrTwoFactorRequired) {
+			return nil, status.Error(codes.FailedPrecondition, "two-factor authentication must be enabled first")
+		}
+
+		if errors.Is(err, auth.ErrInvalidApp) {
+			return nil, status.Error(codes.InvalidArgument, "invalid app_id")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to verify code")
+	}
+
+	return &ssov1.VerifyCodeResponse{
+		Token:              result.AccessToken,
+		RefreshToken:       result.RefreshToken,
+		TwoFactorChallenge: result.TwoFactorChallenge,
+	}, nil
+}
+
+func (s *serverAPI) ChangePassword(
+	ctx context.Context,
+	in *ssov1.ChangePasswordRequest,
+) (*ssov1.ChangePasswordResponse, error) {
+	if in.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	if in.OldPassword == "" {
+		return nil, status.Error(codes.InvalidArgument, "old password is required")
+	}
+
+	if in.NewPassword == "" {
+		return nil, status.Error(codes.InvalidArgument, "new password is required")
+	}
+
+	err := s.auth.ChangePassword(ctx, in.GetToken(), in.GetOldPassword(), in.GetNewPassword())
+	if err != nil {
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			return nil, status.Error(codes.InvalidArgument, policyErr.Error())
+		}
+
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrTokenRevoked) ||
+			errors.Is(err, auth.ErrSessionRevoked) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "invalid old password")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to change password")
+	}
+
+	return &ssov1.ChangePasswordResponse{}, nil
+}
+
+func (s *serverAPI) RequestPasswordReset(
+	ctx context.Context,
+	in *ssov1.RequestPasswordResetRequest,
+) (*ssov1.RequestPasswordResetResponse, error) {
+	if in.Phone == "" {
+		return nil, status.Error(codes.InvalidArgument, "phone is required")
+	}
+
+	err := s.auth.RequestPasswordReset(ctx, in.GetPhone())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidPhone) {
+			return nil, status.Error(codes.InvalidArgument, "invalid phone")
+		}
+
+		var retryErr *auth.RetryAfterError
+		if errors.As(err, &retryErr) {
+			return nil, retryAfterError("code was sent recently", retryErr.RetryAfter)
+		}
+
+		return nil, status.Error(codes.Internal, "failed to send code")
+	}
+
+	return &ssov1.RequestPasswordResetResponse{}, nil
+}
+
+func (s *serverAPI) ResetPassword(
+	ctx context.Context,
+	in *ssov1.ResetPasswordRequest,
+) (*ssov1.ResetPasswordResponse, error) {
+	if in.Phone == "" {
+		return nil, status.Error(codes.InvalidArgument, "phone is required")
+	}
+
+	if in.Code == "" {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+
+	if in.NewPassword == "" {
+		return nil, status.Error(codes.InvalidArgument, "new password is required")
+	}
+
+	err := s.auth.ResetPassword(ctx, in.GetPhone(), in.GetCode(), in.GetNewPassword())
+	if err != nil {
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			return nil, status.Error(codes.InvalidArgument, policyErr.Error())
+		}
+
+		if errors.Is(err, auth.ErrInvalidPhone) {
+			return nil, status.Error(codes.InvalidArgument, "invalid phone")
+		}
+
+		if err := codeError(err); err != nil {
+			return nil, err
+		}
+
+		return nil, status.Error(codes.Internal, "failed to reset password")
+	}
+
+	return &ssov1.ResetPasswordResponse{}, nil
+}
+
+func (s *serverAPI) VerifyTwoFactor(
+	ctx context.Context,
+	in *ssov1.VerifyTwoFactorRequest,
+) (*ssov1.VerifyTwoFactorResponse, error) {
+	if in.Challenge == "" {
+		return nil, status.Error(codes.InvalidArgument, "challenge is required")
+	}
+
+	if in.Code == "" {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+
+	tokens, err := s.auth.VerifyTwoFactor(ctx, in.GetChallenge(), in.GetCode(), clientInfo(ctx))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidChallenge) {
+			return nil, status.Error(codes.Unauthenticated, "invalid challenge")
+		}
+
+		if errors.Is(err, auth.ErrInvalidTwoFactorCode) {
+			return nil, status.Error(codes.InvalidArgument, "invalid code")
+		}
+
+		var retryErr *auth.RetryAfterError
+		if errors.As(err, &retryErr) {
+			return nil, retryAfterError("too many login attempts", retryErr.RetryAfter)
+		}
+
+		return nil, status.Error(codes.Internal, "failed to verify two-factor code")
+	}
+
+	return &ssov1.VerifyTwoFactorResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (s *serverAPI) IsAdmin(
+	ctx context.Context,
+	in *ssov1.IsAdminRequest,
+) (*ssov1.IsAdminResponse, error) {
+	if in.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	isAdmin, err := s.auth.IsAdmin(ctx, in.GetUserId())
+	if err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to check admin status")
+	}
+
+	return &ssov1.IsAdminResponse{IsAdmin: isAdmin}, nil
+}
+
+func (s *serverAPI) GetRoles(
+	ctx context.Context,
+	in *ssov1.GetRolesRequest,
+) (*ssov1.GetRolesResponse, error) {
+	if in.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	roles, err := s.auth.Roles(ctx, in.GetUserId())
+	if err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to get roles")
+	}
+
+	return &ssov1.GetRolesResponse{Roles: roles}, nil
+}
+
+func (s *serverAPI) RegisterGuest(
+	ctx context.Context,
+	in *ssov1.RegisterGuestRequest,
+) (*ssov1.RegisterGuestResponse, error) {
+	if in.DeviceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "device_id is required")
+	}
+
+	if in.AppId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	uid, tokens, err := s.auth.RegisterGuest(ctx, in.GetDeviceId(), int64(in.GetAppId()), clientInfo(ctx))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidDeviceID) {
+			return nil, status.Error(codes.InvalidArgument, "invalid device_id")
+		}
+
+		if errors.Is(err, auth.ErrInvalidApp) {
+			return nil, status.Error(codes.InvalidArgument, "invalid app_id")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to register guest")
+	}
+
+	return &ssov1.RegisterGuestResponse{
+		UserId:       uid,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (s *serverAPI) UpgradeGuest(
+	ctx context.Context,
+	in *ssov1.UpgradeGuestRequest,
+) (*ssov1.UpgradeGuestResponse, error) {
+	if in.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	if in.Phone == "" {
+		return nil, status.Error(codes.InvalidArgument, "phone is required")
+	}
+
+	if in.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "password is required")
+	}
+
+	err := s.auth.UpgradeGuest(ctx, in.GetToken(), in.GetPhone(), in.GetPassword(), clientInfo(ctx))
+	if err != nil {
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			return nil, status.Error(codes.InvalidArgument, policyErr.Error())
+		}
+
+		if errors.Is(err, auth.ErrInvalidPhone) {
+			return nil, status.Error(codes.InvalidArgument, "invalid phone")
+		}
+
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrTokenRevoked) ||
+			errors.Is(err, auth.ErrSessionRevoked) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		if errors.Is(err, auth.ErrNotGuest) {
+			return nil, status.Error(codes.FailedPrecondition, "user is not a guest")
+		}
+
+		if errors.Is(err, storage.ErrUserExists) {
+			return nil, status.Error(codes.AlreadyExists, "user already exists")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to upgrade guest")
+	}
+
+	return &ssov1.UpgradeGuestResponse{}, nil
+}
+
+// ValidateToken lets other services check access tokens without parsing them.
+// A rejected token is not an error: the response tells the reason it is not valid.
+func (s *serverAPI) ValidateToken(
+	ctx context.Context,
+	in *ssov1.ValidateTokenRequest,
+) (*ssov1.ValidateTokenResponse, error) {
+	if in.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	claims, err := s.auth.ValidateToken(ctx, in.GetToken())
+	if err != nil {
+		if reason := auth.InvalidTokenReason(err); reason != "" {
+			return &ssov1.ValidateTokenResponse{Valid: false, Reason: reason}, nil
+		}
+
+		return nil, status.Error(codes.Internal, "failed to validate token")
+	}
+
+	return &ssov1.ValidateTokenResponse{
+		Valid:     true,
+		UserId:    claims.UID,
+		Phone:     claims.Phone,
+		Roles:     claims.Roles,
+		AppId:     claims.AppID,
+		SessionId: claims.SID,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+	}, nil
+}
+
+func (s *serverAPI) GetUser(
+	ctx context.Context,
+	in *ssov1.GetUserRequest,
+) (*ssov1.GetUserResponse, error) {
+	if in.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	user, err := s.auth.User(ctx, in.GetUserId())
+	if err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to get user")
+	}
+
+	return &ssov1.GetUserResponse{
+		UserId: user.ID,
+		Phone:  user.Phone,
+		Name:   user.Name,
+		Image:  user.Image,
+		Roles:  user.Roles,
+		Guest:  user.Guest,
+	}, nil
+}
+
+// codeError maps the errors of one-time code verification to gRPC errors.
+// Returns nil if err is not one of them.
+func codeError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrInvalidCode):
+		return status.Error(codes.InvalidArgument, "invalid code")
+	case errors.Is(err, auth.ErrCodeExpired):
+		return status.Error(codes.InvalidArgument, "code expired")
+	case errors.Is(err, auth.ErrTooManyAttempts):
+		return status.Error(codes.ResourceExhausted, "too many attempts, request a new code")
+	default:
+		return nil
+	}
+}
+
+// retryAfterError returns ResourceExhausted error with RetryInfo details telling the client when to retry.
+func retryAfterError(msg string, retryAfter time.Duration) error {
+	st, err := status.New(codes.ResourceExhausted, msg).WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(retryAfter),
+	})
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, msg)
+	}
+
+	return st.Err()
+}
+
+// Metadata keys the client apps report the device they run on with.
+const (
+	deviceNameKey = "x-device-name"
+	platformKey   = "x-platform"
+)
+
+// clientInfo returns the address of the peer, and the user agent and the device from the request metadata.
+func clientInfo(ctx context.Context) models.ClientInfo {
+	var client models.ClientInfo
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		client.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(client.IP); err == nil {
+			client.IP = host
+		}
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ua := md.Get("user-agent"); len(ua) > 0 {
+			client.UserAgent = ua[0]
+		}
+		if name := md.Get(deviceNameKey); len(name) > 0 {
+			client.DeviceName = name[0]
+		}
+		if platform := md.Get(platformKey); len(platform) > 0 {
+			client.Platform = platform[0]
+		}
+	}
+
+	return client
+}
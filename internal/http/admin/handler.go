@@ -0,0 +1,381 @@
+package adminhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/services/apikeys"
+	"sso/internal/services/audit"
+	"sso/internal/services/auth"
+	"strconv"
+	"time"
+)
+
+type Admin interface {
+	Roles(
+		ctx context.Context,
+		userID int64,
+	) (roles []string, err error)
+	SetRoles(
+		ctx context.Context,
+		userID int64,
+		roles []string,
+	) error
+	RequireTwoFactor(
+		ctx context.Context,
+		userID int64,
+		required bool,
+	) error
+}
+
+type Audit interface {
+	Events(
+		ctx context.Context,
+		filter models.AuditFilter,
+	) (events []models.AuditEvent, err error)
+}
+
+type APIKeys interface {
+	Create(
+		ctx context.Context,
+		appID int64,
+		name string,
+		scopes []string,
+	) (key models.APIKey, plain string, err error)
+	List(
+		ctx context.Context,
+		appID int64,
+	) (keys []models.APIKey, err error)
+	Revoke(
+		ctx context.Context,
+		id int64,
+	) error
+}
+
+type Handler struct {
+	log     *slog.Logger
+	admin   Admin
+	audit   Audit
+	apiKeys APIKeys
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func New(log *slog.Logger, admin Admin, audit Audit, apiKeys APIKeys) *Handler {
+	return &Handler{
+		log:     log,
+		admin:   admin,
+		audit:   audit,
+		apiKeys: apiKeys,
+	}
+}
+
+type rolesBody struct {
+	Roles []string `json:"roles"`
+}
+
+// UserRoles handles the HTTP GET request for the roles of the user.
+func (h *Handler) UserRoles(w http.ResponseWriter, r *http.Request) {
+	const op = "adminhttp.UserRoles"
+
+	userID, ok := userIDVar(w, r)
+	if !ok {
+		return
+	}
+
+	roles, err := h.admin.Roles(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			writeError(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		h.log.Error("failed to get roles", slog.String("op", op), sl.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to get roles")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rolesBody{Roles: roles})
+}
+
+// SetUserRoles handles the HTTP PUT request to replace the roles of the user.
+func (h *Handler) SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	const op = "adminhttp.SetUserRoles"
+
+	userID, ok := userIDVar(w, r)
+	if !ok {
+		return
+	}
+
+	var req rolesBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	err := h.admin.SetRoles(r.Context(), userID, req.Roles)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidRole):
+			writeError(w, http.StatusBadRequest, "invalid role")
+		case errors.Is(err, auth.ErrUserNotFound):
+			writeError(w, http.StatusNotFound, "user not found")
+		default:
+			h.log.Error("failed to set roles", slog.String("op", op), sl.Err(err))
+			writeError(w, http.StatusInternalServerError, "failed to set roles")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type twoFactorRequest struct {
+	Required bool `json:"required"`
+}
+
+// SetTwoFactorRequired handles the HTTP PUT request to set whether the user must use two-factor authentication.
+func (h *Handler) SetTwoFactorRequired(w http.ResponseWriter, r *http.Request) {
+	const op = "adminhttp.SetTwoFactorRequired"
+
+	userID, ok := userIDVar(w, r)
+	if !ok {
+		return
+	}
+
+	var req twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := h.admin.RequireTwoFactor(r.Context(), userID, req.Required); err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			writeError(w, http.StatusNotFound, "user not found")
+			return
+		}
+
+		h.log.Error("failed to set two-factor requirement", slog.String("op", op), sl.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to set two-factor requirement")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type auditEventsResponse struct {
+	Events []models.AuditEvent `json:"events"`
+	// NextBefore is the before parameter of the next page. It is omitted on the last page.
+	NextBefore int64 `json:"nextBefore,omitempty"`
+}
+
+// AuditEvents handles the HTTP GET request for the authentication audit log, newest events first.
+// Events are filtered by the event, outcome, userId, phone, ip, from and to (RFC 3339) query parameters
+// and paginated with limit and before.
+func (h *Handler) AuditEvents(w http.ResponseWriter, r *http.Request) {
+	const op = "adminhttp.AuditEvents"
+
+	filter, err := auditFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	events, err := h.audit.Events(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, audit.ErrInvalidFilter) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		h.log.Error("failed to get audit events", slog.String("op", op), sl.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to get audit events")
+		return
+	}
+
+	resp := auditEventsResponse{Events: events}
+	if limit := filter.Limit; limit == 0 && len(events) == audit.DefaultLimit || limit > 0 && len(events) == limit {
+		resp.NextBefore = events[len(events)-1].ID
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// auditFilter parses the query parameters of the audit log request.
+func auditFilter(r *http.Request) (models.AuditFilter, error) {
+	q := r.URL.Query()
+
+	filter := models.AuditFilter{
+		Event:   q.Get("event"),
+		Outcome: q.Get("outcome"),
+		Phone:   q.Get("phone"),
+		IP:      q.Get("ip"),
+	}
+
+	ints := []struct {
+		name string
+		dst  *int64
+	}{
+		{"userId", &filter.UserID},
+		{"before", &filter.Before},
+	}
+	for _, p := range ints {
+		if v := q.Get(p.name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n <= 0 {
+				return models.AuditFilter{}, fmt.Errorf("invalid %s", p.name)
+			}
+			*p.dst = n
+		}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return models.AuditFilter{}, errors.New("invalid limit")
+		}
+		filter.Limit = n
+	}
+
+	times := []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	}
+	for _, p := range times {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return models.AuditFilter{}, fmt.Errorf("invalid %s", p.name)
+			}
+			*p.dst = t
+		}
+	}
+
+	return filter, nil
+}
+
+type createAPIKeyRequest struct {
+	AppID  int64    `json:"appId"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type createAPIKeyResponse struct {
+	models.APIKey
+	// Key is shown only once, when the key is created.
+	Key string `json:"key"`
+}
+
+// CreateAPIKey handles the HTTP POST request to create an API key of an app with the given scopes.
+// Responds with the key, which can't be shown again.
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	const op = "adminhttp.CreateAPIKey"
+
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.AppID <= 0 || req.Name == "" {
+		writeError(w, http.StatusBadRequest, "appId and name are required")
+		return
+	}
+
+	key, plain, err := h.apiKeys.Create(r.Context(), req.AppID, req.Name, req.Scopes)
+	if err != nil {
+		switch {
+		case errors.Is(err, apikeys.ErrInvalidScope):
+			writeError(w, http.StatusBadRequest, "invalid scope")
+		case errors.Is(err, apikeys.ErrInvalidApp):
+			writeError(w, http.StatusBadRequest, "invalid appId")
+		default:
+			h.log.Error("failed to create api key", slog.String("op", op), sl.Err(err))
+			writeError(w, http.StatusInternalServerError, "failed to create api key")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, createAPIKeyResponse{APIKey: key, Key: plain})
+}
+
+// APIKeys handles the HTTP GET request for API keys, of the app from the appId query parameter if it is set.
+func (h *Handler) APIKeys(w http.ResponseWriter, r *http.Request) {
+	const op = "adminhttp.APIKeys"
+
+	var appID int64
+	if v := r.URL.Query().Get("appId"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid appId")
+			return
+		}
+		appID = n
+	}
+
+	keys, err := h.apiKeys.List(r.Context(), appID)
+	if err != nil {
+		h.log.Error("failed to get api keys", slog.String("op", op), sl.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to get api keys")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// RevokeAPIKey handles the HTTP DELETE request to revoke the API key.
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	const op = "adminhttp.RevokeAPIKey"
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid api key id")
+		return
+	}
+
+	if err := h.apiKeys.Revoke(r.Context(), id); err != nil {
+		if errors.Is(err, apikeys.ErrAPIKeyNotFound) {
+			writeError(w, http.StatusNotFound, "api key not found")
+			return
+		}
+
+		h.log.Error("failed to revoke api key", slog.String("op", op), sl.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to revoke api key")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// userIDVar parses the id path variable. If it is not a valid user ID, responds with 400 and returns false.
+func userIDVar(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || userID <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid user id")
+		return 0, false
+	}
+
+	return userID, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
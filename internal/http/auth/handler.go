@@ -0,0 +1,454 @@
+package authhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sso/internal/domain/models"
+	"sso/internal/lib/clientinfo"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/password"
+	"sso/internal/services/auth"
+	"sso/internal/storage"
+	"strconv"
+)
+
+type Auth interface {
+	RegisterNewUser(
+		ctx context.Context,
+		phone string,
+		password string,
+		appID int64,
+		client models.ClientInfo,
+	) (userID int64, err error)
+	Login(
+		ctx context.Context,
+		phone string,
+		password string,
+		appID int64,
+		client models.ClientInfo,
+	) (result models.LoginResult, err error)
+	ValidateToken(
+		ctx context.Context,
+		accessToken string,
+	) (claims jwt.Claims, err error)
+	Logout(
+		ctx context.Context,
+		accessToken string,
+		refreshToken string,
+	) error
+	ChangePassword(
+		ctx context.Context,
+		accessToken string,
+		oldPassword string,
+		newPassword string,
+	) error
+	EnrollTOTP(
+		ctx context.Context,
+		userID int64,
+	) (enrollment models.TOTPEnrollment, err error)
+	ConfirmTOTP(
+		ctx context.Context,
+		userID int64,
+		code string,
+	) (recoveryCodes []string, err error)
+	DisableTOTP(
+		ctx context.Context,
+		userID int64,
+		code string,
+	) error
+	Sessions(
+		ctx context.Context,
+		userID int64,
+		currentID string,
+	) (sessions []models.Session, err error)
+	RevokeSession(
+		ctx context.Context,
+		userID int64,
+		sessionID string,
+	) error
+}
+
+type Handler struct {
+	log  *slog.Logger
+	auth Auth
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func New(log *slog.Logger, auth Auth) *Handler {
+	return &Handler{
+		log:  log,
+		auth: auth,
+	}
+}
+
+type credentialsRequest struct {
+	Phone    string `json:"phone"`
+	Password string `json:"password"`
+	AppID    int64  `json:"appId"`
+}
+
+type registerResponse struct {
+	UserID int64 `json:"userId"`
+}
+
+type loginResponse struct {
+	AccessToken        string `json:"accessToken,omitempty"`
+	RefreshToken       string `json:"refreshToken,omitempty"`
+	TwoFactorChallenge string `json:"twoFactorChallenge,omitempty"`
+}
+
+// Register handles the HTTP POST request to register a new user with a phone and a password,
+// the same as the Register RPC. Responds with the ID of the user.
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	const op = "authhttp.Register"
+
+	req, ok := decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	uid, err := h.auth.RegisterNewUser(r.Context(), req.Phone, req.Password, req.AppID, clientinfo.FromRequest(r))
+	if err != nil {
+		var policyErr *password.PolicyError
+		switch {
+		case errors.As(err, &policyErr):
+			writeError(w, http.StatusBadRequest, policyErr.Error())
+		case errors.Is(err, auth.ErrInvalidPhone):
+			writeError(w, http.StatusBadRequest, "invalid phone")
+		case errors.Is(err, auth.ErrInvalidApp):
+			writeError(w, http.StatusBadRequest, "invalid appId")
+		case errors.Is(err, storage.ErrUserExists):
+			writeError(w, http.StatusConflict, "user already exists")
+		default:
+			h.log.Error("failed to register user", slog.String("op", op), sl.Err(err))
+			writeError(w, http.StatusInternalServerError, "failed to register user")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, registerResponse{UserID: uid})
+}
+
+// Login handles the HTTP POST request to log in with a phone and a password, the same as the Login RPC.
+// If the second factor is needed, responds with a challenge to pass to the VerifyTwoFactor RPC instead of tokens.
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	const op = "authhttp.Login"
+
+	req, ok := decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.auth.Login(r.Context(), req.Phone, req.Password, req.AppID, clientinfo.FromRequest(r))
+	if err != nil {
+		var retryErr *auth.RetryAfterError
+		switch {
+		case errors.As(err, &retryErr):
+			seconds := int(math.Ceil(retryErr.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			writeError(w, http.StatusTooManyRequests, "too many login attempts")
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			writeError(w, http.StatusUnauthorized, "invalid phone or password")
+		case errors.Is(err, auth.ErrInvalidPhone):
+			writeError(w, http.StatusBadRequest, "invalid phone")
+		case errors.Is(err, auth.ErrInvalidApp):
+			writeError(w, http.StatusBadRequest, "invalid appId")
+		case errors.Is(err, auth.ErrTwoFactorRequired):
+			writeError(w, http.StatusForbidden, "two-factor authentication must be enabled first")
+		default:
+			h.log.Error("failed to login", slog.String("op", op), sl.Err(err))
+			writeError(w, http.StatusInternalServerError, "failed to login")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, loginResponse{
+		AccessToken:        result.AccessToken,
+		RefreshToken:       result.RefreshToken,
+		TwoFactorChallenge: result.TwoFactorChallenge,
+	})
+}
+
+// decodeCredentials decodes the body of the register and login requests.
+// If it is invalid, responds with 400 Bad Request and returns false.
+func decodeCredentials(w http.ResponseWriter, r *http.Request) (credentialsRequest, bool) {
+	var req credentialsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return credentialsRequest{}, false
+	}
+
+	switch {
+	case req.Phone == "":
+		writeError(w, http.StatusBadRequest, "phone is required")
+	case req.Password == "":
+		writeError(w, http.StatusBadRequest, "password is required")
+	case req.AppID == 0:
+		writeError(w, http.StatusBadRequest, "appId is required")
+	default:
+		return req, true
+	}
+
+	return credentialsRequest{}, false
+}
+
+type logoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Logout handles the HTTP POST request to revoke the caller's access token.
+// If the body contains a refresh token, its whole family is revoked as well.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	const op = "authhttp.Logout"
+
+	token, ok := r.Context().Value("token").(string)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "token not found in context")
+		return
+	}
+
+	var req logoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	err := h.auth.Logout(r.Context(), token, req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+
+		h.log.Error("failed to logout", slog.String("op", op), sl.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to logout")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type changePasswordRequest struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+}
+
+// ChangePassword handles the HTTP POST request to change the caller's password.
+// All tokens of the caller are revoked, so the client has to log in again with the new password.
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	const op = "authhttp.ChangePassword"
+
+	token, ok := r.Context().Value("token").(string)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "token not found in context")
+		return
+	}
+
+	var req changePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.OldPassword == "" || req.NewPassword == "" {
+		writeError(w, http.StatusBadRequest, "old and new passwords are required")
+		return
+	}
+
+	err := h.auth.ChangePassword(r.Context(), token, req.OldPassword, req.NewPassword)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrTokenRevoked) {
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			writeError(w, http.StatusForbidden, "invalid old password")
+			return
+		}
+
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			writeError(w, http.StatusBadRequest, policyErr.Error())
+			return
+		}
+
+		h.log.Error("failed to change password", slog.String("op", op), sl.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to change password")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type totpCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// EnrollTOTP handles the HTTP POST request to start enrolling the caller in two-factor authentication.
+// Responds with the secret and the otpauth:// URI to show as a QR code.
+func (h *Handler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "authhttp.EnrollTOTP"
+
+	uid, ok := r.Context().Value("uid").(int64)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "UID not found in context")
+		return
+	}
+
+	enrollment, err := h.auth.EnrollTOTP(r.Context(), uid)
+	if err != nil {
+		if errors.Is(err, auth.ErrTwoFactorEnabled) {
+			writeError(w, http.StatusConflict, "two-factor authentication already enabled")
+			return
+		}
+
+		h.log.Error("failed to enroll totp", slog.String("op", op), sl.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to enroll totp")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, enrollment)
+}
+
+// ConfirmTOTP handles the HTTP POST request to enable two-factor authentication with a code
+// from the authenticator app. Responds with recovery codes, which are shown only once.
+func (h *Handler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "authhttp.ConfirmTOTP"
+
+	uid, ok := r.Context().Value("uid").(int64)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "UID not found in context")
+		return
+	}
+
+	var req totpCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		writeError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	recoveryCodes, err := h.auth.ConfirmTOTP(r.Context(), uid, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+			writeError(w, http.StatusBadRequest, "invalid code")
+		case errors.Is(err, auth.ErrTwoFactorNotEnabled):
+			writeError(w, http.StatusNotFound, "totp enrollment not started")
+		case errors.Is(err, auth.ErrTwoFactorEnabled):
+			writeError(w, http.StatusConflict, "two-factor authentication already enabled")
+		default:
+			h.log.Error("failed to confirm totp", slog.String("op", op), sl.Err(err))
+			writeError(w, http.StatusInternalServerError, "failed to confirm totp")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, recoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// DisableTOTP handles the HTTP DELETE request to disable two-factor authentication of the caller.
+// The body must contain a code from the authenticator app or a recovery code.
+func (h *Handler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "authhttp.DisableTOTP"
+
+	uid, ok := r.Context().Value("uid").(int64)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "UID not found in context")
+		return
+	}
+
+	var req totpCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	err := h.auth.DisableTOTP(r.Context(), uid, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+			writeError(w, http.StatusBadRequest, "invalid code")
+		case errors.Is(err, auth.ErrTwoFactorNotEnabled):
+			writeError(w, http.StatusNotFound, "two-factor authentication not enabled")
+		case errors.Is(err, auth.ErrTwoFactorRequired):
+			writeError(w, http.StatusForbidden, "two-factor authentication is required for the account")
+		default:
+			h.log.Error("failed to disable totp", slog.String("op", op), sl.Err(err))
+			writeError(w, http.StatusInternalServerError, "failed to disable totp")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Sessions handles the HTTP GET request to list the devices the caller is logged in on.
+// The session of the caller's token is marked as current.
+func (h *Handler) Sessions(w http.ResponseWriter, r *http.Request) {
+	uid, ok := r.Context().Value("uid").(int64)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "UID not found in context")
+		return
+	}
+
+	sid, _ := r.Context().Value("sid").(string)
+
+	sessions, err := h.auth.Sessions(r.Context(), uid, sid)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get sessions")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+// RevokeSession handles the HTTP DELETE request to sign the caller out on the device of the session.
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	uid, ok := r.Context().Value("uid").(int64)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "UID not found in context")
+		return
+	}
+
+	err := h.auth.RevokeSession(r.Context(), uid, mux.Vars(r)["id"])
+	if err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			writeError(w, http.StatusNotFound, "session not found")
+			return
+		}
+
+		writeError(w, http.StatusInternalServerError, "failed to revoke session")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(errorResponse{Error: msg})
+}
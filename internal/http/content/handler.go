@@ -0,0 +1,239 @@
+package contenthttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/services/content"
+	"strconv"
+)
+
+type Content interface {
+	CreateAuthor(ctx context.Context, author models.Author) (models.Author, error)
+	UpdateAuthor(ctx context.Context, author models.Author) (models.Author, error)
+	DeleteAuthor(ctx context.Context, id int64) error
+	CreatePoet(ctx context.Context, poet models.Poet) (models.Poet, error)
+	UpdatePoet(ctx context.Context, poet models.Poet) (models.Poet, error)
+	DeletePoet(ctx context.Context, id int64) error
+	CreateArticle(ctx context.Context, article models.Article) (models.Article, error)
+	UpdateArticle(ctx context.Context, article models.Article) (models.Article, error)
+	DeleteArticle(ctx context.Context, id int64) error
+	CreateClip(ctx context.Context, clip models.Clip) (models.Clip, error)
+	UpdateClip(ctx context.Context, clip models.Clip) (models.Clip, error)
+	DeleteClip(ctx context.Context, id int64) error
+	CreateQuiz(ctx context.Context, quiz models.Quiz) (models.Quiz, error)
+	UpdateQuiz(ctx context.Context, quiz models.Quiz) (models.Quiz, error)
+	DeleteQuiz(ctx context.Context, id int64) error
+}
+
+type Handler struct {
+	log     *slog.Logger
+	content Content
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func New(log *slog.Logger, content Content) *Handler {
+	return &Handler{
+		log:     log,
+		content: content,
+	}
+}
+
+// CreateAuthor handles the HTTP POST request to add an author to the catalog.
+func (h *Handler) CreateAuthor(w http.ResponseWriter, r *http.Request) {
+	create(h, w, r, "contenthttp.CreateAuthor", h.content.CreateAuthor)
+}
+
+// UpdateAuthor handles the HTTP PUT request to replace the author.
+func (h *Handler) UpdateAuthor(w http.ResponseWriter, r *http.Request) {
+	update(h, w, r, "contenthttp.UpdateAuthor", func(a *models.Author, id int64) { a.ID = id }, h.content.UpdateAuthor)
+}
+
+// DeleteAuthor handles the HTTP DELETE request to remove the author from the catalog.
+func (h *Handler) DeleteAuthor(w http.ResponseWriter, r *http.Request) {
+	remove(h, w, r, "contenthttp.DeleteAuthor", h.content.DeleteAuthor)
+}
+
+// CreatePoet handles the HTTP POST request to add a poet to the catalog.
+func (h *Handler) CreatePoet(w http.ResponseWriter, r *http.Request) {
+	create(h, w, r, "contenthttp.CreatePoet", h.content.CreatePoet)
+}
+
+// UpdatePoet handles the HTTP PUT request to replace the poet.
+func (h *Handler) UpdatePoet(w http.ResponseWriter, r *http.Request) {
+	update(h, w, r, "contenthttp.UpdatePoet", func(p *models.Poet, id int64) { p.ID = id }, h.content.UpdatePoet)
+}
+
+// DeletePoet handles the HTTP DELETE request to remove the poet from the catalog.
+func (h *Handler) DeletePoet(w http.ResponseWriter, r *http.Request) {
+	remove(h, w, r, "contenthttp.DeletePoet", h.content.DeletePoet)
+}
+
+// CreateArticle handles the HTTP POST request to add an article to the catalog.
+func (h *Handler) CreateArticle(w http.ResponseWriter, r *http.Request) {
+	create(h, w, r, "contenthttp.CreateArticle", h.content.CreateArticle)
+}
+
+// UpdateArticle handles the HTTP PUT request to replace the article.
+func (h *Handler) UpdateArticle(w http.ResponseWriter, r *http.Request) {
+	update(h, w, r, "contenthttp.UpdateArticle", func(a *models.Article, id int64) { a.ID = id }, h.content.UpdateArticle)
+}
+
+// DeleteArticle handles the HTTP DELETE request to remove the article from the catalog.
+func (h *Handler) DeleteArticle(w http.ResponseWriter, r *http.Request) {
+	remove(h, w, r, "contenthttp.DeleteArticle", h.content.DeleteArticle)
+}
+
+// CreateClip handles the HTTP POST request to create a clip with its text. The quiz in the body is ignored.
+func (h *Handler) CreateClip(w http.ResponseWriter, r *http.Request) {
+	create(h, w, r, "contenthttp.CreateClip", h.content.CreateClip)
+}
+
+// UpdateClip handles the HTTP PUT request to replace the image and the text of the clip.
+func (h *Handler) UpdateClip(w http.ResponseWriter, r *http.Request) {
+	update(h, w, r, "contenthttp.UpdateClip", func(c *models.Clip, id int64) { c.ID = id }, h.content.UpdateClip)
+}
+
+// DeleteClip handles the HTTP DELETE request to delete the clip with its quiz.
+func (h *Handler) DeleteClip(w http.ResponseWriter, r *http.Request) {
+	remove(h, w, r, "contenthttp.DeleteClip", h.content.DeleteClip)
+}
+
+// CreateQuiz handles the HTTP POST request to create the quiz of the clip with the ID from the body.
+func (h *Handler) CreateQuiz(w http.ResponseWriter, r *http.Request) {
+	create(h, w, r, "contenthttp.CreateQuiz", h.content.CreateQuiz)
+}
+
+// UpdateQuiz handles the HTTP PUT request to replace the quiz with its answers.
+func (h *Handler) UpdateQuiz(w http.ResponseWriter, r *http.Request) {
+	update(h, w, r, "contenthttp.UpdateQuiz", func(q *models.Quiz, id int64) { q.ID = id }, h.content.UpdateQuiz)
+}
+
+// DeleteQuiz handles the HTTP DELETE request to delete the quiz with its answers.
+func (h *Handler) DeleteQuiz(w http.ResponseWriter, r *http.Request) {
+	remove(h, w, r, "contenthttp.DeleteQuiz", h.content.DeleteQuiz)
+}
+
+// create decodes an entity from the request body, saves it and responds with the stored entity.
+func create[T any](
+	h *Handler,
+	w http.ResponseWriter,
+	r *http.Request,
+	op string,
+	save func(ctx context.Context, entity T) (T, error),
+) {
+	var entity T
+	if err := json.NewDecoder(r.Body).Decode(&entity); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	saved, err := save(r.Context(), entity)
+	if err != nil {
+		h.writeContentError(w, op, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, saved)
+}
+
+// update decodes an entity from the request body, gives it the ID from the path, saves it
+// and responds with the stored entity.
+func update[T any](
+	h *Handler,
+	w http.ResponseWriter,
+	r *http.Request,
+	op string,
+	setID func(entity *T, id int64),
+	save func(ctx context.Context, entity T) (T, error),
+) {
+	id, ok := idVar(w, r)
+	if !ok {
+		return
+	}
+
+	var entity T
+	if err := json.NewDecoder(r.Body).Decode(&entity); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	setID(&entity, id)
+
+	saved, err := save(r.Context(), entity)
+	if err != nil {
+		h.writeContentError(w, op, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, saved)
+}
+
+// remove deletes the entity with the ID from the path.
+func remove(
+	h *Handler,
+	w http.ResponseWriter,
+	r *http.Request,
+	op string,
+	del func(ctx context.Context, id int64) error,
+) {
+	id, ok := idVar(w, r)
+	if !ok {
+		return
+	}
+
+	if err := del(r.Context(), id); err != nil {
+		h.writeContentError(w, op, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeContentError responds with the status matching the error of the content service.
+func (h *Handler) writeContentError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, content.ErrInvalidContent):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, content.ErrClipNotFound):
+		writeError(w, http.StatusBadRequest, "clip not found")
+	case errors.Is(err, content.ErrNotFound):
+		writeError(w, http.StatusNotFound, "not found")
+	case errors.Is(err, content.ErrInUse):
+		writeError(w, http.StatusConflict, "clip is in use")
+	case errors.Is(err, content.ErrQuizExists):
+		writeError(w, http.StatusConflict, "quiz already exists")
+	default:
+		h.log.Error("failed to write content", slog.String("op", op), sl.Err(err))
+		writeError(w, http.StatusInternalServerError, "failed to write content")
+	}
+}
+
+// idVar parses the id path variable. If it is not a valid ID, responds with 400 and returns false.
+func idVar(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return 0, false
+	}
+
+	return id, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
@@ -0,0 +1,141 @@
+package favoriteshttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/services/favorites"
+	"strconv"
+)
+
+type Favorites interface {
+	Set(ctx context.Context, userID int64, contentType string, id int64, fave bool) (models.Favorite, error)
+	List(ctx context.Context, userID int64, query favorites.Query) ([]models.Favorite, string, error)
+}
+
+type Handler struct {
+	log       *slog.Logger
+	favorites Favorites
+}
+
+type listResponse struct {
+	Favorites []models.Favorite `json:"favorites"`
+	// NextCursor is the cursor parameter of the next page. It is omitted on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func New(log *slog.Logger, favorites Favorites) *Handler {
+	return &Handler{
+		log:       log,
+		favorites: favorites,
+	}
+}
+
+// List handles the HTTP GET request for a page of favorites of all types, the recently added first.
+// The type, order, limit and cursor query parameters select the page.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "favoriteshttp.List"
+
+	uid, ok := r.Context().Value("uid").(int64)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "uid not found in context")
+		return
+	}
+
+	q := r.URL.Query()
+
+	query := favorites.Query{
+		Type:   q.Get("type"),
+		Order:  q.Get("order"),
+		Cursor: q.Get("cursor"),
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		query.Limit = n
+	}
+
+	list, next, err := h.favorites.List(r.Context(), uid, query)
+	if err != nil {
+		switch {
+		case errors.Is(err, favorites.ErrInvalidType):
+			writeError(w, http.StatusBadRequest, "invalid content type")
+		case errors.Is(err, favorites.ErrInvalidFilter):
+			writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			h.log.Error("failed to get favorites", slog.String("op", op), sl.Err(err))
+			writeError(w, http.StatusInternalServerError, "failed to get favorites")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{Favorites: list, NextCursor: next})
+}
+
+// Add handles the HTTP PUT request to add the content item with the type and the ID from the path to favorites.
+func (h *Handler) Add(w http.ResponseWriter, r *http.Request) {
+	h.set(w, r, "favoriteshttp.Add", true)
+}
+
+// Remove handles the HTTP DELETE request to remove the content item with the type and the ID from the path
+// from favorites.
+func (h *Handler) Remove(w http.ResponseWriter, r *http.Request) {
+	h.set(w, r, "favoriteshttp.Remove", false)
+}
+
+// set puts the content item from the path in the fave state and responds with the resulting state.
+func (h *Handler) set(w http.ResponseWriter, r *http.Request, op string, fave bool) {
+	uid, ok := r.Context().Value("uid").(int64)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "uid not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	favorite, err := h.favorites.Set(r.Context(), uid, vars["type"], id, fave)
+	if err != nil {
+		switch {
+		case errors.Is(err, favorites.ErrInvalidType):
+			writeError(w, http.StatusBadRequest, "invalid content type")
+		case errors.Is(err, favorites.ErrNotFound):
+			writeError(w, http.StatusNotFound, "not found")
+		default:
+			h.log.Error("failed to set favorite", slog.String("op", op), sl.Err(err))
+			writeError(w, http.StatusInternalServerError, "failed to set favorite")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, favorite)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
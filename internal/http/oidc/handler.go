@@ -0,0 +1,347 @@
+package oidchttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sso/internal/domain/models"
+	"sso/internal/lib/clientinfo"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/services/auth"
+	"sso/internal/services/oidc"
+	"strings"
+)
+
+type OIDC interface {
+	Discovery() oidc.Discovery
+	CheckAuthorizeRequest(
+		ctx context.Context,
+		req models.AuthorizeRequest,
+	) (client models.OAuthClient, err error)
+	Authorize(
+		ctx context.Context,
+		req models.AuthorizeRequest,
+		phone string,
+		password string,
+		clientInfo models.ClientInfo,
+	) (result oidc.AuthorizeResult, err error)
+	AuthorizeTwoFactor(
+		ctx context.Context,
+		req models.AuthorizeRequest,
+		challenge string,
+		code string,
+		clientInfo models.ClientInfo,
+	) (authCode string, err error)
+	Exchange(
+		ctx context.Context,
+		req models.TokenRequest,
+		clientInfo models.ClientInfo,
+	) (tokens models.OAuthTokens, err error)
+	UserInfo(
+		ctx context.Context,
+		accessToken string,
+	) (info models.UserInfo, err error)
+}
+
+type Handler struct {
+	log  *slog.Logger
+	oidc OIDC
+}
+
+func New(log *slog.Logger, oidc OIDC) *Handler {
+	return &Handler{
+		log:  log,
+		oidc: oidc,
+	}
+}
+
+// Discovery handles the HTTP GET request for the OpenID Connect discovery document.
+func (h *Handler) Discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.oidc.Discovery())
+}
+
+// loginPage is the sign-in form of the authorization endpoint. Parameters of the authorization request
+// are passed through hidden fields. If Challenge is set, the form asks for the second factor.
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Вход — {{.ClientName}}</title>
+</head>
+<body>
+<h1>Вход в {{.ClientName}}</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<form method="post">
+{{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}{{if .Challenge}}<input type="hidden" name="two_factor_challenge" value="{{.Challenge}}">
+<label>Код из приложения или код восстановления <input name="two_factor_code" autocomplete="one-time-code" required></label>
+{{else}}<label>Телефон <input name="phone" type="tel" autocomplete="tel" required></label>
+<label>Пароль <input name="password" type="password" autocomplete="current-password" required></label>
+{{end}}<button type="submit">Войти</button>
+</form>
+</body>
+</html>
+`))
+
+type loginPageData struct {
+	ClientName string
+	Params     map[string]string
+	Challenge  string
+	Error      string
+}
+
+// Authorize handles the authorization endpoint. GET shows the sign-in form, and POST signs the user in
+// and redirects back to the client with an authorization code.
+// Requests with an unknown client or redirect URI are rejected without a redirect.
+func (h *Handler) Authorize(w http.ResponseWriter, r *http.Request) {
+	const op = "oidchttp.Authorize"
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	req := authorizeRequest(r.Form)
+
+	client, err := h.oidc.CheckAuthorizeRequest(r.Context(), req)
+	if err != nil {
+		h.authorizeFailed(w, r, req, op, err)
+		return
+	}
+
+	page := loginPageData{
+		ClientName: client.Name,
+		Params:     authorizeParams(req),
+	}
+
+	if r.Method == http.MethodGet {
+		renderLoginPage(w, http.StatusOK, page)
+		return
+	}
+
+	clientInfo := clientinfo.FromRequest(r)
+
+	var code string
+	if challenge := r.PostForm.Get("two_factor_challenge"); challenge != "" {
+		code, err = h.oidc.AuthorizeTwoFactor(r.Context(), req, challenge, r.PostForm.Get("two_factor_code"), clientInfo)
+		if errors.Is(err, auth.ErrInvalidTwoFactorCode) {
+			page.Challenge = challenge
+		}
+	} else {
+		var result oidc.AuthorizeResult
+		result, err = h.oidc.Authorize(r.Context(), req, r.PostForm.Get("phone"), r.PostForm.Get("password"), clientInfo)
+		if err == nil && result.TwoFactorChallenge != "" {
+			page.Challenge = result.TwoFactorChallenge
+			renderLoginPage(w, http.StatusOK, page)
+			return
+		}
+		code = result.Code
+	}
+	if err != nil {
+		var retryErr *auth.RetryAfterError
+		switch {
+		case errors.As(err, &retryErr):
+			page.Error = "Слишком много попыток входа, попробуйте позже"
+			renderLoginPage(w, http.StatusTooManyRequests, page)
+		case errors.Is(err, auth.ErrInvalidCredentials), errors.Is(err, auth.ErrInvalidPhone):
+			page.Error = "Неверный телефон или пароль"
+			renderLoginPage(w, http.StatusUnauthorized, page)
+		case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+			page.Error = "Неверный код"
+			renderLoginPage(w, http.StatusUnauthorized, page)
+		case errors.Is(err, auth.ErrInvalidChallenge):
+			page.Error = "Время на ввод кода истекло, войдите снова"
+			renderLoginPage(w, http.StatusUnauthorized, page)
+		case errors.Is(err, auth.ErrTwoFactorRequired):
+			page.Error = "Сначала настройте двухфакторную аутентификацию в приложении"
+			renderLoginPage(w, http.StatusForbidden, page)
+		default:
+			h.authorizeFailed(w, r, req, op, err)
+		}
+		return
+	}
+
+	redirect(w, r, req, url.Values{"code": {code}})
+}
+
+// authorizeFailed responds to the authorization request that can't be completed.
+// OAuth errors are sent to the redirect URI of the client, others are shown to the user.
+func (h *Handler) authorizeFailed(w http.ResponseWriter, r *http.Request, req models.AuthorizeRequest, op string, err error) {
+	var oauthErr *oidc.Error
+	switch {
+	case errors.Is(err, oidc.ErrInvalidClient):
+		http.Error(w, "Unknown client or redirect URI", http.StatusBadRequest)
+	case errors.As(err, &oauthErr):
+		redirect(w, r, req, url.Values{
+			"error":             {oauthErr.Code},
+			"error_description": {oauthErr.Description},
+		})
+	default:
+		h.log.Error("failed to authorize", slog.String("op", op), sl.Err(err))
+		http.Error(w, "Failed to authorize", http.StatusInternalServerError)
+	}
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type oauthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// Token handles the HTTP POST request to the token endpoint. Clients with a secret authenticate
+// with HTTP Basic authentication or the client_secret parameter, public clients send client_id only.
+func (h *Handler) Token(w http.ResponseWriter, r *http.Request) {
+	const op = "oidchttp.Token"
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, oauthErrorResponse{Error: "invalid_request"})
+		return
+	}
+
+	req := models.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		RefreshToken: r.PostForm.Get("refresh_token"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+	}
+	if id, secret, ok := r.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+
+	tokens, err := h.oidc.Exchange(r.Context(), req, clientinfo.FromRequest(r))
+	if err != nil {
+		var oauthErr *oidc.Error
+		if errors.As(err, &oauthErr) {
+			status := http.StatusBadRequest
+			if oauthErr.Code == "invalid_client" {
+				status = http.StatusUnauthorized
+				w.Header().Set("WWW-Authenticate", `Basic realm="sso"`)
+			}
+
+			writeJSON(w, status, oauthErrorResponse{Error: oauthErr.Code, ErrorDescription: oauthErr.Description})
+			return
+		}
+
+		h.log.Error("failed to exchange tokens", slog.String("op", op), sl.Err(err))
+		writeJSON(w, http.StatusInternalServerError, oauthErrorResponse{Error: "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+		RefreshToken: tokens.RefreshToken,
+		IDToken:      tokens.IDToken,
+		Scope:        tokens.Scope,
+	})
+}
+
+// UserInfo handles the HTTP GET request for the claims about the user the bearer access token is issued to.
+func (h *Handler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	const op = "oidchttp.UserInfo"
+
+	accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || accessToken == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="sso"`)
+		http.Error(w, "Authorization header is missing", http.StatusUnauthorized)
+		return
+	}
+
+	info, err := h.oidc.UserInfo(r.Context(), accessToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrTokenRevoked) ||
+			errors.Is(err, auth.ErrSessionRevoked) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="sso", error="invalid_token"`)
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		h.log.Error("failed to get user info", slog.String("op", op), sl.Err(err))
+		http.Error(w, "Failed to get user info", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+// authorizeRequest returns the authorization request from the query or form parameters.
+func authorizeRequest(params url.Values) models.AuthorizeRequest {
+	return models.AuthorizeRequest{
+		ResponseType:        params.Get("response_type"),
+		ClientID:            params.Get("client_id"),
+		RedirectURI:         params.Get("redirect_uri"),
+		Scope:               params.Get("scope"),
+		State:               params.Get("state"),
+		Nonce:               params.Get("nonce"),
+		CodeChallenge:       params.Get("code_challenge"),
+		CodeChallengeMethod: params.Get("code_challenge_method"),
+	}
+}
+
+// authorizeParams returns the parameters of the authorization request to be passed through the sign-in form.
+func authorizeParams(req models.AuthorizeRequest) map[string]string {
+	return map[string]string{
+		"response_type":         req.ResponseType,
+		"client_id":             req.ClientID,
+		"redirect_uri":          req.RedirectURI,
+		"scope":                 req.Scope,
+		"state":                 req.State,
+		"nonce":                 req.Nonce,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+	}
+}
+
+// redirect redirects the user back to the client with the parameters and the state of the request.
+func redirect(w http.ResponseWriter, r *http.Request, req models.AuthorizeRequest, params url.Values) {
+	target, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		http.Error(w, "Invalid redirect URI", http.StatusBadRequest)
+		return
+	}
+
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	query := target.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	target.RawQuery = query.Encode()
+
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func renderLoginPage(w http.ResponseWriter, status int, page loginPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(status)
+	_ = loginPage.Execute(w, page)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// Cache is an in-memory cache of at most size entries, each of which expires at its own time.
+// It is safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	mu      sync.Mutex
+	entries map[K]entry[V]
+	size    int
+}
+
+func New[K comparable, V any](size int) *Cache[K, V] {
+	return &Cache[K, V]{
+		entries: make(map[K]entry[V]),
+		size:    size,
+	}
+}
+
+// Get returns the value of the key if it is cached and has not expired.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	if !time.Now().Before(e.expiresAt) {
+		delete(c.entries, key)
+
+		var zero V
+		return zero, false
+	}
+
+	return e.value, true
+}
+
+// Set caches the value of the key until expiresAt. If the cache is full, expired entries are dropped,
+// and if there are none, an arbitrary entry is evicted to make room.
+func (c *Cache[K, V]) Set(key K, value V, expiresAt time.Time) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		c.evict()
+	}
+
+	c.entries[key] = entry[V]{value: value, expiresAt: expiresAt}
+}
+
+// Delete removes the key from the cache.
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+// evict drops expired entries or, if there are none, one arbitrary entry. c.mu must be held.
+func (c *Cache[K, V]) evict() {
+	now := time.Now()
+
+	for key, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+
+	if len(c.entries) < c.size {
+		return
+	}
+
+	for key := range c.entries {
+		delete(c.entries, key)
+		return
+	}
+}
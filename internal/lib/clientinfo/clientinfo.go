@@ -0,0 +1,28 @@
+package clientinfo
+
+import (
+	"net"
+	"net/http"
+	"sso/internal/domain/models"
+)
+
+// Headers the client apps report the device they run on with, as the x-device-name and x-platform gRPC metadata.
+const (
+	DeviceNameHeader = "X-Device-Name"
+	PlatformHeader   = "X-Platform"
+)
+
+// FromRequest returns the address the HTTP request came from, its user agent and the device reported by the client.
+func FromRequest(r *http.Request) models.ClientInfo {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	return models.ClientInfo{
+		IP:         ip,
+		UserAgent:  r.UserAgent(),
+		DeviceName: r.Header.Get(DeviceNameHeader),
+		Platform:   r.Header.Get(PlatformHeader),
+	}
+}
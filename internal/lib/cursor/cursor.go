@@ -0,0 +1,35 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Encode returns an opaque URL-safe cursor holding the position of the last item of a page.
+// The position must be a value that encoding/json can marshal, such as a struct of strings and numbers.
+func Encode(position any) string {
+	b, err := json.Marshal(position)
+	if err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode reads the position from a cursor made by Encode into position.
+// If the cursor is malformed, returns ErrInvalidCursor.
+func Decode(cursor string, position any) error {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(b, position); err != nil {
+		return ErrInvalidCursor
+	}
+
+	return nil
+}
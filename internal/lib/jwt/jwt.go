@@ -1,24 +1,194 @@
 package jwt
 
 import (
+	"crypto/x509"
+	"errors"
 	"github.com/golang-jwt/jwt/v5"
 	"sso/internal/domain/models"
+	"sso/internal/lib/secret"
+	"strconv"
 	"time"
 )
 
-// NewToken creates new JWT token for given user and app.
-func NewToken(user models.User, app models.App, duration time.Duration) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+// idSize is the number of random bytes in a token ID (jti).
+const idSize = 16
 
-	claims := token.Claims.(jwt.MapClaims)
-	claims["uid"] = user.ID
-	claims["phone"] = user.Phone
-	claims["exp"] = time.Now().Add(duration).Unix()
+var (
+	ErrKeyIDMissing      = errors.New("kid header is missing")
+	ErrAlgorithmMismatch = errors.New("token algorithm does not match the key")
+)
+
+// KeyFunc returns the key with the given ID to verify a token with.
+type KeyFunc func(kid string) (models.SigningKey, error)
+
+// Claims are the claims of the access tokens issued by the service.
+type Claims struct {
+	UID   int64  `json:"uid"`
+	Phone string `json:"phone"`
+	AppID int64  `json:"app_id"`
+	// SID is the ID of the session the token is issued in.
+	SID string `json:"sid,omitempty"`
+	// Roles are the roles of the user at the time the token was issued.
+	Roles []string `json:"roles,omitempty"`
+	// IssuedAtMs is iat in milliseconds. Tokens issued before it was introduced don't have it.
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
+	jwt.RegisteredClaims
+}
 
-	tokenString, err := token.SignedString([]byte(app.Secret))
+// IssueTime returns the time the token was issued at, to the millisecond if the token has iat_ms.
+// Otherwise it is the start of the second of iat.
+func (c Claims) IssueTime() time.Time {
+	if c.IssuedAtMs != 0 {
+		return time.UnixMilli(c.IssuedAtMs)
+	}
+
+	return c.IssuedAt.Time
+}
+
+// NewToken creates new JWT token for given user and app signed with the key.
+// The token is issued by issuer in the given session and is intended for the app only.
+func NewToken(
+	user models.User,
+	app models.App,
+	sessionID string,
+	key models.SigningKey,
+	issuer string,
+	duration time.Duration,
+) (string, error) {
+	id, err := secret.Generate(idSize)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	claims := Claims{
+		UID:        user.ID,
+		Phone:      user.Phone,
+		AppID:      app.ID,
+		SID:        sessionID,
+		Roles:      user.Roles,
+		IssuedAtMs: now.UnixMilli(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Issuer:    issuer,
+			Audience:  jwt.ClaimStrings{app.Name},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		},
+	}
+
+	return sign(claims, key)
+}
+
+// IDClaims are the claims of OpenID Connect ID tokens.
+type IDClaims struct {
+	Nonce    string `json:"nonce,omitempty"`
+	AuthTime int64  `json:"auth_time"`
+	// PhoneNumber is set only if the client has requested the phone scope.
+	PhoneNumber string `json:"phone_number,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// NewIDToken creates an OpenID Connect ID token of the user for the client signed with the key.
+// The user authenticated at authTime, and nonce is the one the client sent in the authorization request.
+func NewIDToken(
+	userID int64,
+	phone string,
+	clientID string,
+	nonce string,
+	authTime time.Time,
+	key models.SigningKey,
+	issuer string,
+	duration time.Duration,
+) (string, error) {
+	now := time.Now()
+
+	claims := IDClaims{
+		Nonce:       nonce,
+		AuthTime:    authTime.Unix(),
+		PhoneNumber: phone,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(userID, 10),
+			Issuer:    issuer,
+			Audience:  jwt.ClaimStrings{clientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		},
+	}
+
+	return sign(claims, key)
+}
+
+// sign returns the token with the claims signed with the key named in its kid header.
+func sign(claims jwt.Claims, key models.SigningKey) (string, error) {
+	method, err := signingMethod(key.Algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(key.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.ID
+
+	tokenString, err := token.SignedString(privateKey)
 	if err != nil {
 		return "", err
 	}
 
 	return tokenString, nil
 }
+
+// ParseToken verifies the token signature with the key named in its kid header and returns its claims.
+// The token algorithm must match the one of the key; exp is required and, as well as nbf, iss and aud, must be valid.
+func ParseToken(tokenString string, app models.App, issuer string, keyFunc KeyFunc) (Claims, error) {
+	var claims Claims
+
+	verificationKey := func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, ErrKeyIDMissing
+		}
+
+		key, err := keyFunc(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		if token.Method.Alg() != key.Algorithm {
+			return nil, ErrAlgorithmMismatch
+		}
+
+		return x509.ParsePKIXPublicKey(key.PublicKey)
+	}
+
+	_, err := jwt.ParseWithClaims(tokenString, &claims, verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(app.Name),
+	)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	return claims, nil
+}
+
+// AppID returns the app_id claim of the token without verifying it.
+// It is used to pick the app the token must then be verified against.
+func AppID(tokenString string) (int64, error) {
+	var claims Claims
+
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, &claims); err != nil {
+		return 0, err
+	}
+
+	return claims.AppID, nil
+}
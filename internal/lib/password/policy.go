@@ -0,0 +1,95 @@
+package password
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PolicyError describes why a password doesn't satisfy the policy.
+type PolicyError struct {
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return "weak password: " + e.Reason
+}
+
+// Policy is the set of requirements a new password must satisfy.
+type Policy struct {
+	MinLength     int
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Denylist holds lowercased common passwords that are not allowed.
+	Denylist map[string]struct{}
+}
+
+// Validate returns PolicyError if the password doesn't satisfy the policy.
+func (p Policy) Validate(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return &PolicyError{Reason: fmt.Sprintf("must be at least %d characters long", p.MinLength)}
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	switch {
+	case p.RequireLower && !lower:
+		return &PolicyError{Reason: "must contain a lowercase letter"}
+	case p.RequireUpper && !upper:
+		return &PolicyError{Reason: "must contain an uppercase letter"}
+	case p.RequireDigit && !digit:
+		return &PolicyError{Reason: "must contain a digit"}
+	case p.RequireSymbol && !symbol:
+		return &PolicyError{Reason: "must contain a symbol"}
+	}
+
+	if _, ok := p.Denylist[strings.ToLower(password)]; ok {
+		return &PolicyError{Reason: "is too common"}
+	}
+
+	return nil
+}
+
+// LoadDenylist reads common passwords from the file, one per line. Empty lines and lines starting with # are skipped.
+func LoadDenylist(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	denylist := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		denylist[strings.ToLower(line)] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return denylist, nil
+}
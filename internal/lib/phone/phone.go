@@ -0,0 +1,68 @@
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalid = errors.New("invalid phone number")
+
+const (
+	// russianCode is the country code numbers without one are assumed to belong to.
+	russianCode = "7"
+	// russianLen is the number of digits in a Russian number without the country code.
+	russianLen = 10
+	// minLen and maxLen bound the number of digits in an E.164 number, including the country code.
+	minLen = 8
+	maxLen = 15
+)
+
+// Normalize parses the phone number and returns it in E.164 format, e.g. +79161234567.
+// Spaces, dashes, dots and parentheses are ignored. Numbers without an international prefix
+// are treated as Russian: both 89161234567 and 9161234567 become +79161234567.
+func Normalize(raw string) (string, error) {
+	s := strings.TrimSpace(raw)
+
+	international := false
+	switch {
+	case strings.HasPrefix(s, "+"):
+		international = true
+		s = s[1:]
+	case strings.HasPrefix(s, "00"):
+		international = true
+		s = s[2:]
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", ErrInvalid
+		}
+	}
+	digits := b.String()
+
+	if !international {
+		switch {
+		case len(digits) == russianLen:
+			digits = russianCode + digits
+		case len(digits) == russianLen+1 && (digits[0] == '8' || digits[0] == '7'):
+			digits = russianCode + digits[1:]
+		default:
+			return "", ErrInvalid
+		}
+	}
+
+	if len(digits) < minLen || len(digits) > maxLen || digits[0] == '0' {
+		return "", ErrInvalid
+	}
+
+	if strings.HasPrefix(digits, russianCode) && len(digits) != russianLen+1 {
+		return "", ErrInvalid
+	}
+
+	return "+" + digits, nil
+}
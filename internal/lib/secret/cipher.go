@@ -0,0 +1,86 @@
+package secret
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// CipherKeySize is the size of a Cipher key in bytes.
+const CipherKeySize = 32
+
+var (
+	ErrInvalidCipherKey = errors.New("cipher key must be 32 bytes")
+	ErrNoCipherKey      = errors.New("value is sealed, but no cipher key is configured")
+	ErrCorrupted        = errors.New("sealed value is corrupted or sealed with another key")
+)
+
+// sealedPrefix marks sealed values, so that values stored before sealing was enabled are told apart.
+var sealedPrefix = []byte("sealed:v1:")
+
+// Cipher seals values stored at rest with AES-256-GCM.
+// A nil Cipher leaves values as they are and can't open sealed ones.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher returns a Cipher with the key of CipherKeySize bytes.
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != CipherKeySize {
+		return nil, ErrInvalidCipherKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts the value. The same associated data, e.g. the ID of the value, must be passed to Open.
+func (c *Cipher) Seal(value []byte, associatedData []byte) ([]byte, error) {
+	if c == nil {
+		return value, nil
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := append(bytes.Clone(sealedPrefix), nonce...)
+
+	return c.aead.Seal(sealed, nonce, value, associatedData), nil
+}
+
+// Open decrypts a value made by Seal. Values that were not sealed are returned as they are.
+func (c *Cipher) Open(value []byte, associatedData []byte) ([]byte, error) {
+	sealed, ok := bytes.CutPrefix(value, sealedPrefix)
+	if !ok {
+		return value, nil
+	}
+
+	if c == nil {
+		return nil, ErrNoCipherKey
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, ErrCorrupted
+	}
+
+	opened, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], associatedData)
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+
+	return opened, nil
+}
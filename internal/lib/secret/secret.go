@@ -0,0 +1,44 @@
+package secret
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"strings"
+)
+
+// Generate returns a URL-safe random string built from size random bytes.
+func Generate(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Digits returns a random string of n decimal digits, e.g. a one-time code.
+func Digits(n int) (string, error) {
+	var b strings.Builder
+	b.Grow(n)
+
+	for i := 0; i < n; i++ {
+		d, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(byte('0' + d.Int64()))
+	}
+
+	return b.String(), nil
+}
+
+// Hash returns hex-encoded SHA-256 of the given value.
+// It is used to store opaque tokens so that a database leak does not expose them.
+func Hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,109 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogSender logs that a message was sent instead of sending it. It is meant for local development.
+// The text is not logged, since it carries one-time codes.
+type LogSender struct {
+	log *slog.Logger
+}
+
+func NewLogSender(log *slog.Logger) *LogSender {
+	return &LogSender{log: log}
+}
+
+func (s *LogSender) Send(_ context.Context, phone string, text string) error {
+	s.log.Info("sms sent", slog.String("phone", phone), slog.Int("length", len(text)))
+
+	return nil
+}
+
+// FileSender appends messages to a file, one per line, so that tests can read them back.
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+func (s *FileSender) Send(_ context.Context, phone string, text string) error {
+	const op = "sms.FileSender.Send"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	line := strings.Join([]string{time.Now().Format(time.RFC3339), phone, text}, "\t")
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// HTTPSender sends messages through an SMS provider: it posts the phone and the text as JSON to the URL
+// with the API token as a bearer token.
+type HTTPSender struct {
+	url      string
+	apiToken string
+	client   *http.Client
+}
+
+func NewHTTPSender(url string, apiToken string, timeout time.Duration) *HTTPSender {
+	return &HTTPSender{
+		url:      url,
+		apiToken: apiToken,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *HTTPSender) Send(ctx context.Context, phone string, text string) error {
+	const op = "sms.HTTPSender.Send"
+
+	body, err := json.Marshal(struct {
+		Phone string `json:"phone"`
+		Text  string `json:"text"`
+	}{Phone: phone, Text: text})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiToken)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: provider responded with %s", op, resp.Status)
+	}
+
+	return nil
+}
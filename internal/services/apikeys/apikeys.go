@@ -0,0 +1,212 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/secret"
+	"sso/internal/storage"
+	"strings"
+	"time"
+)
+
+// Prefix starts every API key, so that keys can be told apart from JWTs in the Authorization header.
+const Prefix = "sso_"
+
+const (
+	// keySize is the number of random bytes in an API key.
+	keySize = 32
+	// shownPrefixLength is the length of the beginning of a key that is stored to tell keys apart.
+	shownPrefixLength = len(Prefix) + 8
+	// touchInterval limits how often the last used time of a key is updated.
+	touchInterval = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrInvalidScope   = errors.New("invalid scope")
+	ErrInvalidApp     = errors.New("invalid app")
+)
+
+type Storage interface {
+	SaveAPIKey(ctx context.Context, key models.APIKey) (int64, error)
+	APIKey(ctx context.Context, keyHash string) (models.APIKey, error)
+	APIKeys(ctx context.Context, appID int64) ([]models.APIKey, error)
+	TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error
+	DeleteAPIKey(ctx context.Context, id int64) error
+}
+
+type AppProvider interface {
+	App(ctx context.Context, appID int64) (models.App, error)
+}
+
+// APIKeys manages the keys services use to call the API without a user.
+type APIKeys struct {
+	log         *slog.Logger
+	storage     Storage
+	appProvider AppProvider
+}
+
+func New(log *slog.Logger, storage Storage, appProvider AppProvider) *APIKeys {
+	return &APIKeys{
+		log:         log,
+		storage:     storage,
+		appProvider: appProvider,
+	}
+}
+
+// IsAPIKey reports whether the credential is an API key rather than a JWT.
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, Prefix)
+}
+
+// Create creates an API key of the app with the given scopes. Returns the stored key
+// and the key itself, which is not stored and can't be shown again.
+// If any of the scopes can't be granted to API keys, returns ErrInvalidScope.
+func (k *APIKeys) Create(
+	ctx context.Context,
+	appID int64,
+	name string,
+	scopes []string,
+) (models.APIKey, string, error) {
+	const op = "APIKeys.Create"
+
+	log := k.log.With(
+		slog.String("op", op),
+		slog.Int64("app_id", appID),
+	)
+
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return models.APIKey{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := k.appProvider.App(ctx, appID); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.APIKey{}, "", fmt.Errorf("%s: %w", op, ErrInvalidApp)
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return models.APIKey{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	random, err := secret.Generate(keySize)
+	if err != nil {
+		return models.APIKey{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+	plain := Prefix + random
+
+	key := models.APIKey{
+		AppID:     appID,
+		Name:      name,
+		Prefix:    plain[:shownPrefixLength],
+		KeyHash:   secret.Hash(plain),
+		Scopes:    scopes,
+		CreatedAt: time.Now().Truncate(time.Second),
+	}
+
+	key.ID, err = k.storage.SaveAPIKey(ctx, key)
+	if err != nil {
+		log.Error("failed to save api key", sl.Err(err))
+
+		return models.APIKey{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("api key created", slog.Int64("key_id", key.ID), slog.Any("scopes", scopes))
+
+	return key, plain, nil
+}
+
+// List returns API keys of the app, or of all apps if appID is zero.
+func (k *APIKeys) List(ctx context.Context, appID int64) ([]models.APIKey, error) {
+	const op = "APIKeys.List"
+
+	keys, err := k.storage.APIKeys(ctx, appID)
+	if err != nil {
+		k.log.Error("failed to get api keys", slog.String("op", op), sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// Revoke deletes the API key, so that it is rejected from now on. If there is no such key, returns ErrAPIKeyNotFound.
+func (k *APIKeys) Revoke(ctx context.Context, id int64) error {
+	const op = "APIKeys.Revoke"
+
+	log := k.log.With(
+		slog.String("op", op),
+		slog.Int64("key_id", id),
+	)
+
+	if err := k.storage.DeleteAPIKey(ctx, id); err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrAPIKeyNotFound)
+		}
+
+		log.Error("failed to delete api key", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("api key revoked")
+
+	return nil
+}
+
+// Authenticate returns the API key the credential is. If it is not a valid key, returns ErrInvalidAPIKey.
+func (k *APIKeys) Authenticate(ctx context.Context, credential string) (models.APIKey, error) {
+	const op = "APIKeys.Authenticate"
+
+	if !IsAPIKey(credential) {
+		return models.APIKey{}, fmt.Errorf("%s: %w", op, ErrInvalidAPIKey)
+	}
+
+	key, err := k.storage.APIKey(ctx, secret.Hash(credential))
+	if err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			return models.APIKey{}, fmt.Errorf("%s: %w", op, ErrInvalidAPIKey)
+		}
+
+		return models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if now := time.Now(); key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > touchInterval {
+		if err := k.storage.TouchAPIKey(ctx, key.ID, now); err != nil {
+			k.log.Warn("failed to touch api key", slog.String("op", op), sl.Err(err))
+		}
+	}
+
+	return key, nil
+}
+
+// normalizeScopes checks that every scope can be granted to API keys and returns them sorted without duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+
+	set := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		if !models.IsAPIKeyScope(scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+
+		set[scope] = true
+	}
+
+	normalized := make([]string, 0, len(set))
+	for scope := range set {
+		normalized = append(normalized, scope)
+	}
+	sort.Strings(normalized)
+
+	return normalized, nil
+}
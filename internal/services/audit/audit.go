@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/phone"
+	"sso/internal/lib/secret"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLimit is the page size of Events if the filter doesn't set one.
+	DefaultLimit = 50
+	// MaxLimit is the largest page size of Events.
+	MaxLimit = 500
+)
+
+// purgeInterval is how often events older than the retention period are deleted.
+const purgeInterval = time.Hour
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+type Storage interface {
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+	AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+	DeleteAuditEventsBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+// Audit persists authentication events for support and security investigations.
+type Audit struct {
+	log       *slog.Logger
+	storage   Storage
+	retention time.Duration
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+// New returns Audit that keeps events for the retention period. Zero retention keeps them forever.
+func New(log *slog.Logger, storage Storage, retention time.Duration) *Audit {
+	return &Audit{
+		log:       log,
+		storage:   storage,
+		retention: retention,
+	}
+}
+
+// Record saves the event. The phone of the event is stored only as a hash.
+// Failures are logged and not returned, so that auditing never breaks the action being audited.
+// Events older than the retention period are purged from time to time as new ones are recorded.
+func (a *Audit) Record(ctx context.Context, event models.AuditEvent) {
+	const op = "Audit.Record"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("event", event.Event),
+	)
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.Phone != "" {
+		event.PhoneHash = PhoneHash(event.Phone)
+	}
+
+	if err := a.storage.SaveAuditEvent(ctx, event); err != nil {
+		log.Error("failed to save audit event", sl.Err(err))
+	}
+
+	a.purge(ctx)
+}
+
+// Events returns events matching the filter, newest first.
+// If the filter has a phone, it must be a valid one and is matched by its hash.
+func (a *Audit) Events(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	const op = "Audit.Events"
+
+	if filter.Phone != "" {
+		normalized, err := phone.Normalize(filter.Phone)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: %w", op, ErrInvalidFilter, err)
+		}
+
+		filter.PhoneHash = PhoneHash(normalized)
+	}
+
+	switch {
+	case filter.Limit < 0 || filter.Limit > MaxLimit:
+		return nil, fmt.Errorf("%s: %w: limit must be between 1 and %d", op, ErrInvalidFilter, MaxLimit)
+	case filter.Limit == 0:
+		filter.Limit = DefaultLimit
+	}
+
+	events, err := a.storage.AuditEvents(ctx, filter)
+	if err != nil {
+		a.log.Error("failed to get audit events", slog.String("op", op), sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
+
+// purge deletes events older than the retention period if it hasn't been done for purgeInterval.
+func (a *Audit) purge(ctx context.Context) {
+	if a.retention <= 0 {
+		return
+	}
+
+	a.mu.Lock()
+	now := time.Now()
+	if now.Sub(a.lastPurge) < purgeInterval {
+		a.mu.Unlock()
+		return
+	}
+	a.lastPurge = now
+	a.mu.Unlock()
+
+	deleted, err := a.storage.DeleteAuditEventsBefore(ctx, now.Add(-a.retention))
+	if err != nil {
+		a.log.Error("failed to purge audit events", sl.Err(err))
+
+		return
+	}
+
+	if deleted > 0 {
+		a.log.Info("audit events purged", slog.Int64("count", deleted))
+	}
+}
+
+// PhoneHash returns the hash audit events are stored and looked up with instead of the phone in E.164 format.
+func PhoneHash(phone string) string {
+	return secret.Hash("audit:" + phone)
+}
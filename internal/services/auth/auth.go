@@ -4,125 +4,735 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"golang.org/x/crypto/bcrypt"
 	"log/slog"
 	"sso/internal/domain/models"
+	"sso/internal/lib/cache"
 	"sso/internal/lib/jwt"
 	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/password"
+	"sso/internal/lib/phone"
+	"sso/internal/lib/secret"
 	"sso/internal/storage"
 	"time"
 )
 
 type Auth struct {
-	log         *slog.Logger
-	usrSaver    UserSaver
-	usrProvider UserProvider
-	appProvider AppProvider
-	tokenTTL    time.Duration
+	log              *slog.Logger
+	usrSaver         UserSaver
+	usrProvider      UserProvider
+	appProvider      AppProvider
+	tokenStorage     RefreshTokenStorage
+	tokenRevoker     TokenRevoker
+	keyProvider      KeyProvider
+	otpStorage       OTPStorage
+	smsSender        SMSSender
+	otpSettings      OTPSettings
+	loginAttempts    LoginAttemptStorage
+	loginThrottle    LoginThrottleSettings
+	passwordHasher   *password.Hasher
+	passwordPolicy   password.Policy
+	twoFactorStorage TwoFactorStorage
+	twoFactor        TwoFactorSettings
+	sessionStorage   SessionStorage
+	roleStorage      RoleStorage
+	guestStorage     GuestStorage
+	auditor          Auditor
+	introspection    IntrospectionSettings
+	claimsCache      *cache.Cache[string, jwt.Claims]
+	userCache        *cache.Cache[int64, models.User]
+	tokenIssuer      string
+	tokenTTL         time.Duration
+	refreshTokenTTL  time.Duration
 }
 
 var (
-	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrTokenRevoked        = errors.New("token revoked")
+	ErrInvalidApp          = errors.New("invalid app")
+	ErrInvalidPhone        = errors.New("invalid phone")
 )
 
+// refreshTokenSize is the number of random bytes in a refresh token.
+const refreshTokenSize = 32
+
 type UserSaver interface {
 	SaveUser(
 		ctx context.Context,
 		phone string,
 		passHash []byte,
 	) (uid int64, err error)
+	UpdatePassHash(
+		ctx context.Context,
+		userID int64,
+		passHash []byte,
+	) error
 }
 
 type UserProvider interface {
 	User(ctx context.Context, phone string) (models.User, error)
+	UserByID(ctx context.Context, userID int64) (models.User, error)
 }
 
 type AppProvider interface {
-	App(ctx context.Context) (models.App, error)
+	App(ctx context.Context, appID int64) (models.App, error)
+	LongestAppTokenTTL(ctx context.Context) (time.Duration, error)
+}
+
+type RefreshTokenStorage interface {
+	SaveRefreshToken(ctx context.Context, token models.RefreshToken) error
+	RefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, usedID int64, next models.RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+}
+
+type KeyProvider interface {
+	SigningKey(ctx context.Context) (models.SigningKey, error)
+	VerificationKey(ctx context.Context, kid string) (models.SigningKey, error)
+}
+
+type TokenRevoker interface {
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string, userID int64, issuedAt time.Time) (bool, error)
+	RevokeUserTokens(ctx context.Context, userID int64, expiresAt time.Time) error
+}
+
+type Auditor interface {
+	Record(ctx context.Context, event models.AuditEvent)
+}
+
+// Deps are the storages and services Auth depends on.
+type Deps struct {
+	UserSaver        UserSaver
+	UserProvider     UserProvider
+	AppProvider      AppProvider
+	TokenStorage     RefreshTokenStorage
+	TokenRevoker     TokenRevoker
+	KeyProvider      KeyProvider
+	OTPStorage       OTPStorage
+	SMSSender        SMSSender
+	LoginAttempts    LoginAttemptStorage
+	PasswordHasher   *password.Hasher
+	TwoFactorStorage TwoFactorStorage
+	SessionStorage   SessionStorage
+	RoleStorage      RoleStorage
+	GuestStorage     GuestStorage
+	Auditor          Auditor
+}
+
+// Settings configure Auth.
+type Settings struct {
+	OTP            OTPSettings
+	LoginThrottle  LoginThrottleSettings
+	PasswordPolicy password.Policy
+	TwoFactor      TwoFactorSettings
+	Introspection  IntrospectionSettings
+	// TokenIssuer is the iss claim of access tokens.
+	TokenIssuer string
+	// TokenTTL is the lifetime of access tokens of apps that don't override it.
+	TokenTTL        time.Duration
+	RefreshTokenTTL time.Duration
 }
 
-func New(
-	log *slog.Logger,
-	userSaver UserSaver,
-	userProvider UserProvider,
-	appProvider AppProvider,
-	tokenTTL time.Duration,
-) *Auth {
+func New(log *slog.Logger, deps Deps, settings Settings) *Auth {
 	return &Auth{
-		usrSaver:    userSaver,
-		usrProvider: userProvider,
-		log:         log,
-		appProvider: appProvider,
-		tokenTTL:    tokenTTL,
+		log:              log,
+		usrSaver:         deps.UserSaver,
+		usrProvider:      deps.UserProvider,
+		appProvider:      deps.AppProvider,
+		tokenStorage:     deps.TokenStorage,
+		tokenRevoker:     deps.TokenRevoker,
+		keyProvider:      deps.KeyProvider,
+		otpStorage:       deps.OTPStorage,
+		smsSender:        deps.SMSSender,
+		otpSettings:      settings.OTP,
+		loginAttempts:    deps.LoginAttempts,
+		loginThrottle:    settings.LoginThrottle,
+		passwordHasher:   deps.PasswordHasher,
+		passwordPolicy:   settings.PasswordPolicy,
+		twoFactorStorage: deps.TwoFactorStorage,
+		twoFactor:        settings.TwoFactor,
+		sessionStorage:   deps.SessionStorage,
+		roleStorage:      deps.RoleStorage,
+		guestStorage:     deps.GuestStorage,
+		auditor:          deps.Auditor,
+		introspection:    settings.Introspection,
+		claimsCache:      cache.New[string, jwt.Claims](settings.Introspection.cacheSize()),
+		userCache:        cache.New[int64, models.User](settings.Introspection.cacheSize()),
+		tokenIssuer:      settings.TokenIssuer,
+		tokenTTL:         settings.TokenTTL,
+		refreshTokenTTL:  settings.RefreshTokenTTL,
 	}
 }
 
-// Login checks if user with given credentials exists in the system and returns access token.
+// Login checks if user with given credentials exists in the system and returns access and refresh tokens
+// issued for the given app.
 // If user exists, but password is incorrect, returns error.
 // If user doesn't exist, returns error.
+// Failed logins are counted per phone and per client IP, and after too many of them
+// further logins are blocked for a while with RetryAfterError.
+// If the user has two-factor authentication enabled or is required to, returns a challenge
+// to pass to VerifyTwoFactor instead of tokens.
 func (a *Auth) Login(
 	ctx context.Context,
 	phone string,
 	password string,
-) (string, error) {
+	appID int64,
+	client models.ClientInfo,
+) (models.LoginResult, error) {
 	const op = "Auth.Login"
 
+	phone, err := normalizePhone(phone)
+	if err != nil {
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
 	log := a.log.With(
 		slog.String("op", op),
 		slog.String("username", phone),
+		slog.String("ip", client.IP),
 	)
 
 	log.Info("attempting to login user")
 
+	if err := a.checkLoginAllowed(ctx, phone, client); err != nil {
+		var retryErr *RetryAfterError
+		if errors.As(err, &retryErr) {
+			log.Warn("login blocked", sl.Err(err))
+			a.audit(ctx, models.AuditEvent{
+				Event:   models.AuditEventLogin,
+				Phone:   phone,
+				Outcome: models.AuditOutcomeFailure,
+				Reason:  "too_many_attempts",
+			}, client)
+		} else {
+			log.Error("failed to check login attempts", sl.Err(err))
+		}
+
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
 	user, err := a.usrProvider.User(ctx, phone)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			a.log.Warn("user not found", sl.Err(err))
+			a.audit(ctx, models.AuditEvent{
+				Event:   models.AuditEventLogin,
+				Phone:   phone,
+				Outcome: models.AuditOutcomeFailure,
+				Reason:  "user_not_found",
+			}, client)
 
-			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+			return models.LoginResult{}, a.loginFailed(ctx, op, phone, client)
 		}
 
 		a.log.Error("failed to get user", sl.Err(err))
 
-		return "", fmt.Errorf("%s: %w", op, err)
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
-		a.log.Info("invalid credentials", sl.Err(err))
+	ok, needsRehash, err := a.passwordHasher.Verify(user.PassHash, password)
+	if err != nil {
+		a.log.Warn("failed to verify password", sl.Err(err))
+	}
+	if err != nil || !ok {
+		a.log.Info("invalid credentials")
+		a.audit(ctx, models.AuditEvent{
+			Event:   models.AuditEventLogin,
+			UserID:  user.ID,
+			Phone:   phone,
+			Outcome: models.AuditOutcomeFailure,
+			Reason:  "invalid_password",
+		}, client)
+
+		return models.LoginResult{}, a.loginFailed(ctx, op, phone, client)
+	}
+
+	app, err := a.app(ctx, appID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidApp) {
+			a.audit(ctx, models.AuditEvent{
+				Event:   models.AuditEventLogin,
+				UserID:  user.ID,
+				Phone:   phone,
+				Outcome: models.AuditOutcomeFailure,
+				Reason:  "invalid_app",
+			}, client)
+		}
+
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
 
-		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	if needsRehash {
+		a.rehashPassword(ctx, user.ID, password)
 	}
 
-	app, err := a.appProvider.App(ctx)
+	// Failures are reset only once the second factor is passed too, so that guessing codes stays throttled.
+	result, needed, err := a.secondFactor(ctx, user, app)
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		if errors.Is(err, ErrTwoFactorRequired) {
+			log.Info("two-factor authentication required but not enabled")
+			a.audit(ctx, models.AuditEvent{
+				Event:   models.AuditEventLogin,
+				UserID:  user.ID,
+				Phone:   phone,
+				Outcome: models.AuditOutcomeFailure,
+				Reason:  "two_factor_not_enabled",
+			}, client)
+		} else {
+			log.Error("failed to issue two-factor challenge", sl.Err(err))
+		}
+
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if needed {
+		log.Info("password accepted, two-factor challenge issued")
+		a.audit(ctx, models.AuditEvent{
+			Event:   models.AuditEventLogin,
+			UserID:  user.ID,
+			Phone:   phone,
+			Outcome: models.AuditOutcomeSuccess,
+			Reason:  "two_factor_challenge",
+		}, client)
+
+		return result, nil
+	}
+
+	if err := a.loginAttempts.ResetLoginFailures(ctx, throttleKindPhone, phone); err != nil {
+		log.Error("failed to reset login attempts", sl.Err(err))
+
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	log.Info("user logged in successfully")
 
-	token, err := jwt.NewToken(user, app, a.tokenTTL)
+	tokens, err := a.signIn(ctx, user, app, client)
+	if err != nil {
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.audit(ctx, models.AuditEvent{
+		Event:   models.AuditEventLogin,
+		UserID:  user.ID,
+		Phone:   phone,
+		Outcome: models.AuditOutcomeSuccess,
+	}, client)
+
+	return models.LoginResult{TokenPair: tokens}, nil
+}
+
+// rehashPassword replaces the user's password hash with a hash made with the current algorithm and parameters.
+// Failures are only logged, because the old hash keeps working.
+func (a *Auth) rehashPassword(ctx context.Context, userID int64, pass string) {
+	log := a.log.With(slog.Int64("uid", userID))
+
+	passHash, err := a.passwordHasher.Hash(pass)
+	if err != nil {
+		log.Error("failed to generate password hash", sl.Err(err))
+
+		return
+	}
+
+	if err := a.usrSaver.UpdatePassHash(ctx, userID, passHash); err != nil {
+		log.Error("failed to update password hash", sl.Err(err))
+
+		return
+	}
+
+	log.Info("password rehashed")
+}
+
+// loginFailed records the failed login and returns ErrInvalidCredentials wrapped with op.
+func (a *Auth) loginFailed(ctx context.Context, op string, phone string, client models.ClientInfo) error {
+	if err := a.recordLoginFailure(ctx, phone, client); err != nil {
+		a.log.Error("failed to record login attempt", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+}
+
+// Refresh exchanges a refresh token for a new pair of tokens and rotates the refresh token.
+// If an already rotated refresh token is presented again, the whole token family is revoked,
+// because it means the token has leaked.
+// The session of the token family is extended, and created for families started before sessions were recorded.
+func (a *Auth) Refresh(ctx context.Context, refreshToken string, client models.ClientInfo) (models.TokenPair, error) {
+	const op = "Auth.Refresh"
+
+	log := a.log.With(slog.String("op", op))
+
+	stored, err := a.tokenStorage.RefreshToken(ctx, secret.Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenNotFound) {
+			log.Warn("refresh token not found", sl.Err(err))
+
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+		}
+
+		log.Error("failed to get refresh token", sl.Err(err))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", stored.UserID))
+
+	if stored.Revoked {
+		log.Warn("refresh token revoked")
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+	}
+
+	if stored.Used {
+		log.Warn("refresh token reuse detected, revoking token family")
+
+		return models.TokenPair{}, a.revokeFamily(ctx, op, stored.FamilyID)
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		log.Info("refresh token expired")
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+	}
+
+	user, err := a.usrProvider.UserByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+		}
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, err := a.app(ctx, stored.AppID)
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens, next, err := a.issueTokens(ctx, user, app, stored.FamilyID)
+	if err != nil {
+		log.Error("failed to generate tokens", sl.Err(err))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.tokenStorage.RotateRefreshToken(ctx, stored.ID, next); err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenUsed) {
+			log.Warn("refresh token reuse detected, revoking token family")
+
+			return models.TokenPair{}, a.revokeFamily(ctx, op, stored.FamilyID)
+		}
+
+		log.Error("failed to rotate refresh token", sl.Err(err))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.extendSession(ctx, next, client); err != nil {
+		log.Error("failed to extend session", sl.Err(err))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("tokens refreshed")
+
+	return tokens, nil
+}
+
+// ValidateToken verifies the access token against the app it was issued for
+// and checks that neither the token nor its session has been revoked. Returns the token claims.
+// Verified claims are cached briefly, but revocation is checked on every call.
+// If the token is rejected, InvalidTokenReason tells why.
+func (a *Auth) ValidateToken(ctx context.Context, accessToken string) (jwt.Claims, error) {
+	const op = "Auth.ValidateToken"
+
+	claims, err := a.verifiedClaims(ctx, accessToken)
+	if err != nil {
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	revoked, err := a.tokenRevoker.IsTokenRevoked(ctx, claims.ID, claims.UID, claims.IssueTime())
+	if err != nil {
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if revoked {
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrTokenRevoked)
+	}
+
+	if err := a.checkSession(ctx, claims); err != nil {
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return claims, nil
+}
+
+// Logout revokes the access token with its session and, if given, the family of the refresh token issued with it.
+func (a *Auth) Logout(ctx context.Context, accessToken string, refreshToken string) error {
+	const op = "Auth.Logout"
+
+	log := a.log.With(slog.String("op", op))
+
+	claims, err := a.parseToken(ctx, accessToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			log.Info("invalid access token", sl.Err(err))
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", claims.UID))
+
+	if claims.ID != "" {
+		if err := a.tokenRevoker.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			log.Error("failed to revoke access token", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	err = a.sessionStorage.RevokeSession(ctx, claims.UID, claims.SID)
+	if err != nil && !errors.Is(err, storage.ErrSessionNotFound) {
+		log.Error("failed to revoke session", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if refreshToken != "" {
+		stored, err := a.tokenStorage.RefreshToken(ctx, secret.Hash(refreshToken))
+		switch {
+		case errors.Is(err, storage.ErrRefreshTokenNotFound):
+			log.Warn("refresh token not found", sl.Err(err))
+		case err != nil:
+			log.Error("failed to get refresh token", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		case stored.UserID == claims.UID:
+			if err := a.tokenStorage.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+				log.Error("failed to revoke refresh token family", sl.Err(err))
+
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	log.Info("user logged out")
+
+	return nil
+}
+
+// parseToken verifies the access token with the key from its kid header
+// against the app from its app_id claim.
+func (a *Auth) parseToken(ctx context.Context, accessToken string) (jwt.Claims, error) {
+	appID, err := jwt.AppID(accessToken)
+	if err != nil {
+		return jwt.Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	app, err := a.app(ctx, appID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidApp) {
+			return jwt.Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		}
+
+		return jwt.Claims{}, err
+	}
+
+	keyFunc := func(kid string) (models.SigningKey, error) {
+		return a.keyProvider.VerificationKey(ctx, kid)
+	}
+
+	claims, err := jwt.ParseToken(accessToken, app, a.tokenIssuer, keyFunc)
 	if err != nil {
-		a.log.Error("failed to generate token", sl.Err(err))
+		return jwt.Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
 
-		return "", fmt.Errorf("%s: %w", op, err)
+	// Every token is issued in a session, so that it can be signed out remotely.
+	if claims.UID == 0 || claims.IssuedAt == nil || claims.SID == "" {
+		return jwt.Claims{}, fmt.Errorf("%w: uid, iat or sid claim is missing", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// audit records the authentication event of the client.
+func (a *Auth) audit(ctx context.Context, event models.AuditEvent, client models.ClientInfo) {
+	event.IP = client.IP
+	event.UserAgent = client.UserAgent
+
+	a.auditor.Record(ctx, event)
+}
+
+// normalizePhone returns the phone in E.164 format. If it is not a valid phone number, returns ErrInvalidPhone.
+func normalizePhone(raw string) (string, error) {
+	normalized, err := phone.Normalize(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidPhone, err)
 	}
 
-	return token, nil
+	return normalized, nil
 }
 
-// RegisterNewUser registers new user in the system and returns user ID.
+// app returns app by id. If there is no such app, returns ErrInvalidApp.
+func (a *Auth) app(ctx context.Context, appID int64) (models.App, error) {
+	app, err := a.appProvider.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.App{}, fmt.Errorf("%w: %w", ErrInvalidApp, err)
+		}
+
+		return models.App{}, err
+	}
+
+	return app, nil
+}
+
+// signIn starts a new session of the user on the client with a new refresh token family
+// and returns the first pair of tokens of it.
+func (a *Auth) signIn(ctx context.Context, user models.User, app models.App, client models.ClientInfo) (models.TokenPair, error) {
+	familyID, err := secret.Generate(refreshTokenSize)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	tokens, refreshToken, err := a.issueTokens(ctx, user, app, familyID)
+	if err != nil {
+		a.log.Error("failed to generate tokens", sl.Err(err))
+
+		return models.TokenPair{}, err
+	}
+
+	if err := a.sessionStorage.SaveSession(ctx, newSession(refreshToken, client)); err != nil {
+		a.log.Error("failed to save session", sl.Err(err))
+
+		return models.TokenPair{}, err
+	}
+
+	if err := a.tokenStorage.SaveRefreshToken(ctx, refreshToken); err != nil {
+		a.log.Error("failed to save refresh token", sl.Err(err))
+
+		return models.TokenPair{}, err
+	}
+
+	return tokens, nil
+}
+
+// issueTokens creates access token and refresh token of the given family.
+// The family ID is also the ID of the session the access token is issued in,
+// and the access token carries the current roles of the user.
+// The refresh token is returned both in plain form and as a record to be stored.
+func (a *Auth) issueTokens(
+	ctx context.Context,
+	user models.User,
+	app models.App,
+	familyID string,
+) (models.TokenPair, models.RefreshToken, error) {
+	tokenTTL := a.tokenTTL
+	if app.TokenTTL > 0 {
+		tokenTTL = app.TokenTTL
+	}
+
+	roles, err := a.roleStorage.UserRoles(ctx, user.ID)
+	if err != nil {
+		return models.TokenPair{}, models.RefreshToken{}, err
+	}
+	user.Roles = roles
+
+	key, err := a.keyProvider.SigningKey(ctx)
+	if err != nil {
+		return models.TokenPair{}, models.RefreshToken{}, err
+	}
+
+	accessToken, err := jwt.NewToken(user, app, familyID, key, a.tokenIssuer, tokenTTL)
+	if err != nil {
+		return models.TokenPair{}, models.RefreshToken{}, err
+	}
+
+	refreshToken, err := secret.Generate(refreshTokenSize)
+	if err != nil {
+		return models.TokenPair{}, models.RefreshToken{}, err
+	}
+
+	stored := models.RefreshToken{
+		UserID:    user.ID,
+		AppID:     app.ID,
+		FamilyID:  familyID,
+		TokenHash: secret.Hash(refreshToken),
+		ExpiresAt: time.Now().Add(a.refreshTokenTTL),
+	}
+
+	return models.TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, stored, nil
+}
+
+// revokeFamily revokes the refresh token family and returns ErrInvalidRefreshToken wrapped with op.
+func (a *Auth) revokeFamily(ctx context.Context, op string, familyID string) error {
+	if err := a.tokenStorage.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+		a.log.Error("failed to revoke refresh token family", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+}
+
+// RevokeUserTokens revokes every token issued to the user so far. The revocation is kept as long as
+// the longest-living access token may be valid, including those of apps with their own token TTL.
+func (a *Auth) RevokeUserTokens(ctx context.Context, userID int64) error {
+	const op = "Auth.RevokeUserTokens"
+
+	ttl, err := a.appProvider.LongestAppTokenTTL(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.tokenRevoker.RevokeUserTokens(ctx, userID, time.Now().Add(max(ttl, a.tokenTTL))); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RegisterNewUser registers new user of the given app in the system and returns user ID.
 // If user with given username already exists, returns error.
-func (a *Auth) RegisterNewUser(ctx context.Context, phone string, pass string) (int64, error) {
+// If the password doesn't satisfy the password policy, returns password.PolicyError.
+func (a *Auth) RegisterNewUser(
+	ctx context.Context,
+	phone string,
+	pass string,
+	appID int64,
+	client models.ClientInfo,
+) (int64, error) {
 	const op = "Auth.RegisterNewUser"
 
+	phone, err := normalizePhone(phone)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
 	log := a.log.With(
 		slog.String("op", op),
 		slog.String("phone", phone),
+		slog.Int64("app_id", appID),
 	)
 
 	log.Info("registering user")
 
-	passHash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	if _, err := a.app(ctx, appID); err != nil {
+		log.Warn("failed to get app", sl.Err(err))
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.passwordPolicy.Validate(pass); err != nil {
+		log.Info("password rejected by policy", sl.Err(err))
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	passHash, err := a.passwordHasher.Hash(pass)
 	if err != nil {
 		log.Error("failed to generate password hash", sl.Err(err))
 
@@ -133,8 +743,24 @@ func (a *Auth) RegisterNewUser(ctx context.Context, phone string, pass string) (
 	if err != nil {
 		log.Error("failed to save user", sl.Err(err))
 
+		if errors.Is(err, storage.ErrUserExists) {
+			a.audit(ctx, models.AuditEvent{
+				Event:   models.AuditEventRegister,
+				Phone:   phone,
+				Outcome: models.AuditOutcomeFailure,
+				Reason:  "user_exists",
+			}, client)
+		}
+
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
+	a.audit(ctx, models.AuditEvent{
+		Event:   models.AuditEventRegister,
+		UserID:  id,
+		Phone:   phone,
+		Outcome: models.AuditOutcomeSuccess,
+	}, client)
+
 	return id, nil
 }
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/storage"
+)
+
+var (
+	ErrInvalidDeviceID = errors.New("invalid device id")
+	ErrNotGuest        = errors.New("user is not a guest")
+)
+
+// maxDeviceIDLength limits the length of device IDs guests are bound to.
+const maxDeviceIDLength = 256
+
+type GuestStorage interface {
+	SaveGuest(ctx context.Context, deviceID string) (uid int64, err error)
+	Guest(ctx context.Context, deviceID string) (models.User, error)
+	UpgradeGuest(ctx context.Context, userID int64, phone string, passHash []byte) error
+}
+
+// RegisterGuest signs in the guest user bound to the device and returns tokens issued for the given app,
+// so that the catalog can be browsed without registration. The guest is created on the first call from the device,
+// and later calls from it sign in the same guest until it is upgraded with UpgradeGuest.
+func (a *Auth) RegisterGuest(
+	ctx context.Context,
+	deviceID string,
+	appID int64,
+	client models.ClientInfo,
+) (int64, models.TokenPair, error) {
+	const op = "Auth.RegisterGuest"
+
+	if deviceID == "" || len(deviceID) > maxDeviceIDLength {
+		return 0, models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidDeviceID)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("app_id", appID),
+	)
+
+	app, err := a.app(ctx, appID)
+	if err != nil {
+		log.Warn("failed to get app", sl.Err(err))
+
+		return 0, models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.guest(ctx, deviceID, client)
+	if err != nil {
+		log.Error("failed to get guest", sl.Err(err))
+
+		return 0, models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens, err := a.signIn(ctx, user, app, client)
+	if err != nil {
+		return 0, models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("guest signed in", slog.Int64("uid", user.ID))
+
+	return user.ID, tokens, nil
+}
+
+// UpgradeGuest turns the guest the access token is issued to into a registered user with the phone and password.
+// The user keeps its ID, favorites and progress, and its tokens keep working.
+// If the user is not a guest, returns ErrNotGuest.
+// If the phone belongs to another user, returns storage.ErrUserExists.
+// If the password doesn't satisfy the password policy, returns password.PolicyError.
+func (a *Auth) UpgradeGuest(
+	ctx context.Context,
+	accessToken string,
+	phone string,
+	pass string,
+	client models.ClientInfo,
+) error {
+	const op = "Auth.UpgradeGuest"
+
+	phone, err := normalizePhone(phone)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("phone", phone),
+	)
+
+	if err := a.passwordPolicy.Validate(pass); err != nil {
+		log.Info("password rejected by policy", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	claims, err := a.ValidateToken(ctx, accessToken)
+	if err != nil {
+		log.Info("invalid access token", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", claims.UID))
+
+	user, err := a.usrProvider.UserByID(ctx, claims.UID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !user.Guest {
+		return fmt.Errorf("%s: %w", op, ErrNotGuest)
+	}
+
+	passHash, err := a.passwordHasher.Hash(pass)
+	if err != nil {
+		log.Error("failed to generate password hash", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.guestStorage.UpgradeGuest(ctx, user.ID, phone, passHash); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrUserExists):
+			log.Info("phone is taken", sl.Err(err))
+			a.audit(ctx, models.AuditEvent{
+				Event:   models.AuditEventGuestUpgrade,
+				UserID:  user.ID,
+				Phone:   phone,
+				Outcome: models.AuditOutcomeFailure,
+				Reason:  "user_exists",
+			}, client)
+
+			return fmt.Errorf("%s: %w", op, err)
+		case errors.Is(err, storage.ErrUserNotFound):
+			return fmt.Errorf("%s: %w", op, ErrNotGuest)
+		}
+
+		log.Error("failed to upgrade guest", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.userCache.Delete(user.ID)
+
+	log.Info("guest upgraded")
+	a.audit(ctx, models.AuditEvent{
+		Event:   models.AuditEventGuestUpgrade,
+		UserID:  user.ID,
+		Phone:   phone,
+		Outcome: models.AuditOutcomeSuccess,
+	}, client)
+
+	return nil
+}
+
+// guest returns the guest bound to the device, saving a new one if there is none.
+func (a *Auth) guest(ctx context.Context, deviceID string, client models.ClientInfo) (models.User, error) {
+	user, err := a.guestStorage.Guest(ctx, deviceID)
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		return user, err
+	}
+
+	id, err := a.guestStorage.SaveGuest(ctx, deviceID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserExists) {
+			return a.guestStorage.Guest(ctx, deviceID)
+		}
+
+		return models.User{}, err
+	}
+
+	a.log.Info("guest registered", slog.Int64("uid", id))
+	a.audit(ctx, models.AuditEvent{
+		Event:   models.AuditEventRegister,
+		UserID:  id,
+		Outcome: models.AuditOutcomeSuccess,
+		Reason:  "guest",
+	}, client)
+
+	return models.User{ID: id, Guest: true}, nil
+}
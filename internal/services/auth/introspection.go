@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/secret"
+	"sso/internal/storage"
+	"time"
+)
+
+// IntrospectionSettings configure caching of token validation and user lookups done for other services.
+type IntrospectionSettings struct {
+	// CacheTTL is how long verified token claims and users are cached. Zero disables caching.
+	CacheTTL time.Duration
+	// CacheSize is the maximum number of cached tokens, and separately of cached users.
+	CacheSize int
+}
+
+// cacheSize returns the size of the caches, which is zero if caching is disabled.
+func (s IntrospectionSettings) cacheSize() int {
+	if s.CacheTTL <= 0 {
+		return 0
+	}
+
+	return s.CacheSize
+}
+
+// Reasons of token rejection returned by InvalidTokenReason.
+const (
+	TokenReasonMalformed        = "malformed"
+	TokenReasonExpired          = "expired"
+	TokenReasonNotYetValid      = "not_yet_valid"
+	TokenReasonInvalidSignature = "invalid_signature"
+	TokenReasonInvalidIssuer    = "invalid_issuer"
+	TokenReasonInvalidAudience  = "invalid_audience"
+	TokenReasonUnknownApp       = "unknown_app"
+	TokenReasonRevoked          = "revoked"
+	TokenReasonSessionRevoked   = "session_revoked"
+)
+
+// InvalidTokenReason returns why ValidateToken rejected a token, or an empty string if err is not a rejection.
+func InvalidTokenReason(err error) string {
+	switch {
+	case errors.Is(err, ErrTokenRevoked):
+		return TokenReasonRevoked
+	case errors.Is(err, ErrSessionRevoked):
+		return TokenReasonSessionRevoked
+	case !errors.Is(err, ErrInvalidToken):
+		return ""
+	case errors.Is(err, ErrInvalidApp):
+		return TokenReasonUnknownApp
+	case errors.Is(err, gojwt.ErrTokenExpired):
+		return TokenReasonExpired
+	case errors.Is(err, gojwt.ErrTokenNotValidYet), errors.Is(err, gojwt.ErrTokenUsedBeforeIssued):
+		return TokenReasonNotYetValid
+	case errors.Is(err, gojwt.ErrTokenInvalidIssuer):
+		return TokenReasonInvalidIssuer
+	case errors.Is(err, gojwt.ErrTokenInvalidAudience):
+		return TokenReasonInvalidAudience
+	case errors.Is(err, gojwt.ErrTokenSignatureInvalid), errors.Is(err, gojwt.ErrTokenUnverifiable):
+		return TokenReasonInvalidSignature
+	default:
+		return TokenReasonMalformed
+	}
+}
+
+// ForgetUser drops the user from the cache, so that the next introspection reads them from the storage.
+// Services that change the account outside Auth call it, e.g. on deletion.
+func (a *Auth) ForgetUser(userID int64) {
+	a.userCache.Delete(userID)
+}
+
+// User returns the user with their current roles. If there is no such user, returns ErrUserNotFound.
+// Users are cached for IntrospectionSettings.CacheTTL, so changes made by another instance
+// of the service may take that long to show up.
+func (a *Auth) User(ctx context.Context, userID int64) (models.User, error) {
+	const op = "Auth.User"
+
+	if user, ok := a.userCache.Get(userID); ok {
+		return user, nil
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("uid", userID),
+	)
+
+	user, err := a.usrProvider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	roles, err := a.roleStorage.UserRoles(ctx, userID)
+	if err != nil {
+		log.Error("failed to get roles", sl.Err(err))
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user.PassHash = nil
+	user.Roles = roles
+
+	a.userCache.Set(userID, user, time.Now().Add(a.introspection.CacheTTL))
+
+	return user, nil
+}
+
+// verifiedClaims returns the claims of the access token verified by parseToken.
+// Claims are cached for IntrospectionSettings.CacheTTL, but never past the token expiry.
+// Revocation is not cached, the caller checks it every time.
+func (a *Auth) verifiedClaims(ctx context.Context, accessToken string) (jwt.Claims, error) {
+	key := secret.Hash(accessToken)
+
+	if claims, ok := a.claimsCache.Get(key); ok {
+		return claims, nil
+	}
+
+	claims, err := a.parseToken(ctx, accessToken)
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	expiresAt := time.Now().Add(a.introspection.CacheTTL)
+	if claims.ExpiresAt.Time.Before(expiresAt) {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	a.claimsCache.Set(key, claims, expiresAt)
+
+	return claims, nil
+}
@@ -0,0 +1,315 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/secret"
+	"sso/internal/storage"
+	"time"
+)
+
+var (
+	ErrInvalidCode     = errors.New("invalid code")
+	ErrCodeExpired     = errors.New("code expired")
+	ErrTooManyAttempts = errors.New("too many attempts")
+	ErrResendCooldown  = errors.New("code was sent recently")
+)
+
+// OTPSettings configure one-time codes sent by SMS.
+type OTPSettings struct {
+	// Length is the number of digits in a code.
+	Length int
+	// TTL is how long a code stays valid.
+	TTL time.Duration
+	// MaxAttempts is the number of wrong codes after which the code is no longer accepted.
+	MaxAttempts int
+	// ResendCooldown is how long a new code can't be requested for the same phone.
+	ResendCooldown time.Duration
+}
+
+// RetryAfterError is returned when an action is temporarily not allowed.
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%v, retry after %s", e.Err, e.RetryAfter)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+type OTPStorage interface {
+	SaveOTP(ctx context.Context, otp models.OTP) error
+	OTP(ctx context.Context, phone string, purpose string) (models.OTP, error)
+	UseOTPAttempt(ctx context.Context, phone string, purpose string, maxAttempts int) error
+	DeleteOTP(ctx context.Context, phone string, purpose string) error
+}
+
+type SMSSender interface {
+	Send(ctx context.Context, phone string, text string) error
+}
+
+// RequestCode sends a one-time login code to the phone.
+// If a code has been sent to the phone recently or the client has requested too many codes, returns RetryAfterError.
+func (a *Auth) RequestCode(ctx context.Context, phone string, client models.ClientInfo) error {
+	const op = "Auth.RequestCode"
+
+	phone, err := normalizePhone(phone)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("phone", phone),
+		slog.String("ip", client.IP),
+	)
+
+	if err := a.throttleCodeRequest(ctx, client); err != nil {
+		var retryErr *RetryAfterError
+		if errors.As(err, &retryErr) {
+			log.Warn("code request blocked", sl.Err(err))
+		} else {
+			log.Error("failed to check code requests", sl.Err(err))
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.sendCode(ctx, phone, models.OTPPurposeLogin); err != nil {
+		var retryErr *RetryAfterError
+		if !errors.As(err, &retryErr) {
+			log.Error("failed to send code", sl.Err(err))
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("login code sent")
+
+	return nil
+}
+
+// VerifyCode checks the one-time login code sent to the phone and returns tokens issued for the given app.
+// If there is no user with the phone yet, the user is registered without a password.
+// As with Login, a two-factor challenge is returned instead of tokens if the second factor is needed.
+func (a *Auth) VerifyCode(
+	ctx context.Context,
+	phone string,
+	code string,
+	appID int64,
+	client models.ClientInfo,
+) (models.LoginResult, error) {
+	const op = "Auth.VerifyCode"
+
+	phone, err := normalizePhone(phone)
+	if err != nil {
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("phone", phone),
+		slog.Int64("app_id", appID),
+	)
+
+	app, err := a.app(ctx, appID)
+	if err != nil {
+		log.Warn("failed to get app", sl.Err(err))
+
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.checkCode(ctx, phone, code, models.OTPPurposeLogin); err != nil {
+		log.Info("code not accepted", sl.Err(err))
+
+		if reason := codeReason(err); reason != "" {
+			a.audit(ctx, models.AuditEvent{
+				Event:   models.AuditEventLoginCode,
+				Phone:   phone,
+				Outcome: models.AuditOutcomeFailure,
+				Reason:  reason,
+			}, client)
+		}
+
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.usrProvider.User(ctx, phone)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		user, err = a.registerByPhone(ctx, phone, client)
+	}
+	if err != nil {
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result, needed, err := a.secondFactor(ctx, user, app)
+	if err != nil {
+		if errors.Is(err, ErrTwoFactorRequired) {
+			log.Info("two-factor authentication required but not enabled", slog.Int64("uid", user.ID))
+			a.audit(ctx, models.AuditEvent{
+				Event:   models.AuditEventLoginCode,
+				UserID:  user.ID,
+				Phone:   phone,
+				Outcome: models.AuditOutcomeFailure,
+				Reason:  "two_factor_not_enabled",
+			}, client)
+		} else {
+			log.Error("failed to issue two-factor challenge", sl.Err(err))
+		}
+
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if needed {
+		log.Info("code accepted, two-factor challenge issued", slog.Int64("uid", user.ID))
+		a.audit(ctx, models.AuditEvent{
+			Event:   models.AuditEventLoginCode,
+			UserID:  user.ID,
+			Phone:   phone,
+			Outcome: models.AuditOutcomeSuccess,
+			Reason:  "two_factor_challenge",
+		}, client)
+
+		return result, nil
+	}
+
+	tokens, err := a.signIn(ctx, user, app, client)
+	if err != nil {
+		return models.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user logged in with code", slog.Int64("uid", user.ID))
+	a.audit(ctx, models.AuditEvent{
+		Event:   models.AuditEventLoginCode,
+		UserID:  user.ID,
+		Phone:   phone,
+		Outcome: models.AuditOutcomeSuccess,
+	}, client)
+
+	return models.LoginResult{TokenPair: tokens}, nil
+}
+
+// sendCode generates a new code of the given purpose, saves its hash and sends the code by SMS.
+func (a *Auth) sendCode(ctx context.Context, phone string, purpose string) error {
+	now := time.Now()
+
+	prev, err := a.otpStorage.OTP(ctx, phone, purpose)
+	switch {
+	case err == nil:
+		if retryAfter := prev.SentAt.Add(a.otpSettings.ResendCooldown).Sub(now); retryAfter > 0 {
+			return &RetryAfterError{Err: ErrResendCooldown, RetryAfter: retryAfter}
+		}
+	case !errors.Is(err, storage.ErrOTPNotFound):
+		return err
+	}
+
+	code, err := secret.Digits(a.otpSettings.Length)
+	if err != nil {
+		return err
+	}
+
+	err = a.otpStorage.SaveOTP(ctx, models.OTP{
+		Phone:     phone,
+		Purpose:   purpose,
+		CodeHash:  codeHash(phone, code),
+		ExpiresAt: now.Add(a.otpSettings.TTL),
+		SentAt:    now,
+	})
+	if err != nil {
+		return err
+	}
+
+	return a.smsSender.Send(ctx, phone, fmt.Sprintf("Код подтверждения: %s", code))
+}
+
+// checkCode verifies the code of the given purpose and deletes it, so that every code is accepted only once.
+// Every attempt is counted before the code is compared, and after OTPSettings.MaxAttempts of them
+// the code is no longer accepted.
+func (a *Auth) checkCode(ctx context.Context, phone string, code string, purpose string) error {
+	otp, err := a.otpStorage.OTP(ctx, phone, purpose)
+	if err != nil {
+		if errors.Is(err, storage.ErrOTPNotFound) {
+			return ErrInvalidCode
+		}
+
+		return err
+	}
+
+	if time.Now().After(otp.ExpiresAt) {
+		return ErrCodeExpired
+	}
+
+	if err := a.otpStorage.UseOTPAttempt(ctx, phone, purpose, a.otpSettings.MaxAttempts); err != nil {
+		if errors.Is(err, storage.ErrOTPAttemptsExhausted) {
+			return ErrTooManyAttempts
+		}
+
+		return err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(otp.CodeHash), []byte(codeHash(phone, code))) != 1 {
+		return ErrInvalidCode
+	}
+
+	if err := a.otpStorage.DeleteOTP(ctx, phone, purpose); err != nil {
+		if errors.Is(err, storage.ErrOTPNotFound) {
+			return ErrInvalidCode
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// registerByPhone saves a new user without a password. Such a user can only log in with a code.
+func (a *Auth) registerByPhone(ctx context.Context, phone string, client models.ClientInfo) (models.User, error) {
+	id, err := a.usrSaver.SaveUser(ctx, phone, []byte{})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserExists) {
+			return a.usrProvider.User(ctx, phone)
+		}
+
+		return models.User{}, err
+	}
+
+	a.log.Info("user registered with code", slog.Int64("uid", id))
+	a.audit(ctx, models.AuditEvent{
+		Event:   models.AuditEventRegister,
+		UserID:  id,
+		Phone:   phone,
+		Outcome: models.AuditOutcomeSuccess,
+		Reason:  "code",
+	}, client)
+
+	return models.User{ID: id, Phone: phone}, nil
+}
+
+// codeReason returns the audit reason of a rejected code, or an empty string if err is not a rejection.
+func codeReason(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidCode):
+		return "invalid_code"
+	case errors.Is(err, ErrCodeExpired):
+		return "code_expired"
+	case errors.Is(err, ErrTooManyAttempts):
+		return "too_many_attempts"
+	default:
+		return ""
+	}
+}
+
+// codeHash returns the hash of the code bound to the phone it was sent to.
+func codeHash(phone string, code string) string {
+	return secret.Hash(phone + ":" + code)
+}
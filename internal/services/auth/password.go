@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/storage"
+)
+
+// ChangePassword sets a new password of the user the access token is issued to.
+// The old password must match and the new one must satisfy the password policy. All tokens of the user, including the given one, are revoked.
+func (a *Auth) ChangePassword(ctx context.Context, accessToken string, oldPassword string, newPassword string) error {
+	const op = "Auth.ChangePassword"
+
+	log := a.log.With(slog.String("op", op))
+
+	if err := a.passwordPolicy.Validate(newPassword); err != nil {
+		log.Info("password rejected by policy", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	claims, err := a.ValidateToken(ctx, accessToken)
+	if err != nil {
+		log.Info("invalid access token", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", claims.UID))
+
+	user, err := a.usrProvider.UserByID(ctx, claims.UID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	ok, _, err := a.passwordHasher.Verify(user.PassHash, oldPassword)
+	if err != nil {
+		log.Warn("failed to verify password", sl.Err(err))
+	}
+	if err != nil || !ok {
+		log.Info("invalid credentials")
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	if err := a.setPassword(ctx, user.ID, newPassword); err != nil {
+		log.Error("failed to set password", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password changed")
+
+	return nil
+}
+
+// RequestPasswordReset sends a password reset code to the phone.
+// Nothing is sent if there is no user with the phone, but no error is returned either,
+// so that the method can't be used to find out registered phones.
+// If a code has been sent to the phone recently, returns RetryAfterError.
+func (a *Auth) RequestPasswordReset(ctx context.Context, phone string) error {
+	const op = "Auth.RequestPasswordReset"
+
+	phone, err := normalizePhone(phone)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("phone", phone),
+	)
+
+	if _, err := a.usrProvider.User(ctx, phone); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found, reset code is not sent")
+
+			return nil
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.sendCode(ctx, phone, models.OTPPurposePasswordReset); err != nil {
+		var retryErr *RetryAfterError
+		if !errors.As(err, &retryErr) {
+			log.Error("failed to send code", sl.Err(err))
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password reset code sent")
+
+	return nil
+}
+
+// ResetPassword sets a new password of the user with the phone if the reset code sent to it is valid
+// and the password satisfies the password policy.
+// All tokens of the user are revoked.
+func (a *Auth) ResetPassword(ctx context.Context, phone string, code string, newPassword string) error {
+	const op = "Auth.ResetPassword"
+
+	phone, err := normalizePhone(phone)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("phone", phone),
+	)
+
+	if err := a.passwordPolicy.Validate(newPassword); err != nil {
+		log.Info("password rejected by policy", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.checkCode(ctx, phone, code, models.OTPPurposePasswordReset); err != nil {
+		log.Info("code not accepted", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.usrProvider.User(ctx, phone)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrInvalidCode)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.setPassword(ctx, user.ID, newPassword); err != nil {
+		log.Error("failed to set password", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password reset", slog.Int64("uid", user.ID))
+
+	return nil
+}
+
+// setPassword saves the hash of the new password and revokes all tokens of the user.
+func (a *Auth) setPassword(ctx context.Context, userID int64, pass string) error {
+	passHash, err := a.passwordHasher.Hash(pass)
+	if err != nil {
+		return err
+	}
+
+	if err := a.usrSaver.UpdatePassHash(ctx, userID, passHash); err != nil {
+		return err
+	}
+
+	return a.RevokeUserTokens(ctx, userID)
+}
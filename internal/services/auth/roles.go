@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/storage"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrInvalidRole  = errors.New("invalid role")
+)
+
+type RoleStorage interface {
+	UserRoles(ctx context.Context, userID int64) ([]string, error)
+	SetUserRoles(ctx context.Context, userID int64, roles []string) error
+}
+
+// Roles returns the roles of the user. If there is no such user, returns ErrUserNotFound.
+func (a *Auth) Roles(ctx context.Context, userID int64) ([]string, error) {
+	const op = "Auth.Roles"
+
+	if _, err := a.usrProvider.UserByID(ctx, userID); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	roles, err := a.roleStorage.UserRoles(ctx, userID)
+	if err != nil {
+		a.log.Error("failed to get roles", slog.String("op", op), slog.Int64("uid", userID), sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return roles, nil
+}
+
+// IsAdmin checks if user is admin. If there is no such user, returns ErrUserNotFound.
+func (a *Auth) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	const op = "Auth.IsAdmin"
+
+	roles, err := a.Roles(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, role := range roles {
+		if role == models.RoleAdmin {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// SetRoles replaces the roles of the user. Every user keeps models.RoleUser.
+// New roles get into the user's tokens on the next refresh. If a role is taken away,
+// all tokens of the user are revoked, so that it can't be used until they expire.
+func (a *Auth) SetRoles(ctx context.Context, userID int64, roles []string) error {
+	const op = "Auth.SetRoles"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("uid", userID),
+	)
+
+	granted := map[string]bool{models.RoleUser: true}
+	for _, role := range roles {
+		if !models.IsRole(role) {
+			return fmt.Errorf("%s: %w: %q", op, ErrInvalidRole, role)
+		}
+
+		granted[role] = true
+	}
+
+	current, err := a.Roles(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	roles = make([]string, 0, len(granted))
+	for role := range granted {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	if err := a.roleStorage.SetUserRoles(ctx, userID, roles); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to set roles", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, role := range current {
+		if granted[role] {
+			continue
+		}
+
+		if err := a.RevokeUserTokens(ctx, userID); err != nil {
+			log.Error("failed to revoke tokens", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		break
+	}
+
+	a.userCache.Delete(userID)
+
+	log.Info("roles changed", slog.Any("roles", roles))
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/storage"
+	"time"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionRevoked  = errors.New("session revoked")
+)
+
+// sessionTouchInterval limits how often the last seen time of a session is updated on token validation.
+const sessionTouchInterval = time.Minute
+
+type SessionStorage interface {
+	SaveSession(ctx context.Context, session models.Session) error
+	Session(ctx context.Context, id string) (models.Session, error)
+	Sessions(ctx context.Context, userID int64) ([]models.Session, error)
+	TouchSession(ctx context.Context, id string, seenAt time.Time) error
+	ExtendSession(ctx context.Context, id string, expiresAt time.Time) error
+	RevokeSession(ctx context.Context, userID int64, id string) error
+}
+
+// Sessions returns active sessions of the user, most recently seen first.
+// The session with currentID, if any, is marked as current.
+func (a *Auth) Sessions(ctx context.Context, userID int64, currentID string) ([]models.Session, error) {
+	const op = "Auth.Sessions"
+
+	sessions, err := a.sessionStorage.Sessions(ctx, userID)
+	if err != nil {
+		a.log.Error("failed to get sessions", slog.String("op", op), slog.Int64("uid", userID), sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession signs the user out on the device of the session:
+// its refresh tokens stop working and its access tokens are rejected by ValidateToken.
+// If the user has no such active session, returns ErrSessionNotFound.
+func (a *Auth) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	const op = "Auth.RevokeSession"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("uid", userID),
+	)
+
+	if err := a.sessionStorage.RevokeSession(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrSessionNotFound)
+		}
+
+		log.Error("failed to revoke session", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("session revoked")
+
+	return nil
+}
+
+// newSession returns the session started with the first refresh token of a family on the client.
+func newSession(refreshToken models.RefreshToken, client models.ClientInfo) models.Session {
+	now := time.Now()
+
+	return models.Session{
+		ID:         refreshToken.FamilyID,
+		UserID:     refreshToken.UserID,
+		AppID:      refreshToken.AppID,
+		DeviceName: client.DeviceName,
+		Platform:   client.Platform,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  refreshToken.ExpiresAt,
+	}
+}
+
+// extendSession extends the session of the rotated refresh token until the new token expires.
+// Token families started before sessions were recorded get a session on the client they are refreshed from.
+func (a *Auth) extendSession(ctx context.Context, refreshToken models.RefreshToken, client models.ClientInfo) error {
+	err := a.sessionStorage.ExtendSession(ctx, refreshToken.FamilyID, refreshToken.ExpiresAt)
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		return a.sessionStorage.SaveSession(ctx, newSession(refreshToken, client))
+	}
+
+	return err
+}
+
+// checkSession returns ErrSessionRevoked if the session the token is issued in has been revoked,
+// and updates the time the session was last seen at.
+func (a *Auth) checkSession(ctx context.Context, claims jwt.Claims) error {
+	session, err := a.sessionStorage.Session(ctx, claims.SID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return ErrSessionRevoked
+		}
+
+		return err
+	}
+
+	if session.Revoked || session.UserID != claims.UID {
+		return ErrSessionRevoked
+	}
+
+	if now := time.Now(); now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err := a.sessionStorage.TouchSession(ctx, session.ID, now); err != nil {
+			a.log.Warn("failed to touch session", sl.Err(err))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sso/internal/domain/models"
+	"time"
+)
+
+var (
+	ErrTooManyLoginAttempts = errors.New("too many login attempts")
+	ErrTooManyCodeRequests  = errors.New("too many code requests")
+)
+
+// Kinds of subjects failed logins and code requests are counted for.
+const (
+	throttleKindPhone = "phone"
+	throttleKindIP    = "ip"
+	// throttleKindCodeIP counts login codes requested from a client IP, whatever phones they are sent to.
+	throttleKindCodeIP = "code_ip"
+)
+
+type LoginAttemptStorage interface {
+	LoginBlockedUntil(ctx context.Context, kind string, subject string) (time.Time, error)
+	RecordLoginFailure(ctx context.Context, kind string, subject string, at time.Time, resetBefore time.Time) (int, error)
+	BlockLogin(ctx context.Context, kind string, subject string, until time.Time) error
+	ResetLoginFailures(ctx context.Context, kind string, subject string) error
+}
+
+// ThrottleSettings configure how logins are slowed down after failed attempts.
+type ThrottleSettings struct {
+	// FreeAttempts is the number of failures in a row allowed without a delay.
+	FreeAttempts int
+	// BaseDelay is the delay after the first failure beyond FreeAttempts. It doubles with every next failure.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay.
+	MaxDelay time.Duration
+	// LockoutThreshold is the number of failures in a row after which logins are locked out for LockoutDuration.
+	// Zero disables the lockout.
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// ResetAfter is how long a failure is remembered.
+	ResetAfter time.Duration
+}
+
+// LoginThrottleSettings configure throttling of logins per phone and per client IP.
+type LoginThrottleSettings struct {
+	Phone ThrottleSettings
+	IP    ThrottleSettings
+	// CodeRequests throttle login codes requested from a client IP. Every request counts, not only failures.
+	CodeRequests ThrottleSettings
+}
+
+// delay returns how long logins are blocked after the given number of failures in a row.
+func (s ThrottleSettings) delay(failures int) time.Duration {
+	if s.LockoutThreshold > 0 && failures >= s.LockoutThreshold {
+		return s.LockoutDuration
+	}
+
+	if failures <= s.FreeAttempts {
+		return 0
+	}
+
+	delay := s.BaseDelay
+	for i := s.FreeAttempts + 1; i < failures && delay < s.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, s.MaxDelay)
+}
+
+type throttleSubject struct {
+	kind     string
+	subject  string
+	settings ThrottleSettings
+}
+
+// throttleSubjects returns the subjects failed logins of the phone from the client are counted for.
+func (a *Auth) throttleSubjects(phone string, client models.ClientInfo) []throttleSubject {
+	subjects := []throttleSubject{
+		{kind: throttleKindPhone, subject: phone, settings: a.loginThrottle.Phone},
+	}
+
+	if client.IP != "" {
+		subjects = append(subjects, throttleSubject{kind: throttleKindIP, subject: client.IP, settings: a.loginThrottle.IP})
+	}
+
+	return subjects
+}
+
+// checkLoginAllowed returns RetryAfterError if logins of the phone or from the client are blocked.
+func (a *Auth) checkLoginAllowed(ctx context.Context, phone string, client models.ClientInfo) error {
+	var retryAfter time.Duration
+
+	for _, s := range a.throttleSubjects(phone, client) {
+		blockedUntil, err := a.loginAttempts.LoginBlockedUntil(ctx, s.kind, s.subject)
+		if err != nil {
+			return err
+		}
+
+		retryAfter = max(retryAfter, time.Until(blockedUntil))
+	}
+
+	if retryAfter > 0 {
+		return &RetryAfterError{Err: ErrTooManyLoginAttempts, RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// recordLoginFailure counts the failed login of the phone from the client and blocks further logins
+// if there have been too many failures.
+func (a *Auth) recordLoginFailure(ctx context.Context, phone string, client models.ClientInfo) error {
+	now := time.Now()
+
+	for _, s := range a.throttleSubjects(phone, client) {
+		failures, err := a.loginAttempts.RecordLoginFailure(ctx, s.kind, s.subject, now, now.Add(-s.settings.ResetAfter))
+		if err != nil {
+			return err
+		}
+
+		if delay := s.settings.delay(failures); delay > 0 {
+			if err := a.loginAttempts.BlockLogin(ctx, s.kind, s.subject, now.Add(delay)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// throttleCodeRequest returns RetryAfterError if the client has requested too many login codes recently,
+// and otherwise counts the request, so that one client can't send SMS to many phones.
+func (a *Auth) throttleCodeRequest(ctx context.Context, client models.ClientInfo) error {
+	if client.IP == "" {
+		return nil
+	}
+
+	blockedUntil, err := a.loginAttempts.LoginBlockedUntil(ctx, throttleKindCodeIP, client.IP)
+	if err != nil {
+		return err
+	}
+
+	if retryAfter := time.Until(blockedUntil); retryAfter > 0 {
+		return &RetryAfterError{Err: ErrTooManyCodeRequests, RetryAfter: retryAfter}
+	}
+
+	settings := a.loginThrottle.CodeRequests
+	now := time.Now()
+
+	requests, err := a.loginAttempts.RecordLoginFailure(ctx, throttleKindCodeIP, client.IP, now, now.Add(-settings.ResetAfter))
+	if err != nil {
+		return err
+	}
+
+	if delay := settings.delay(requests); delay > 0 {
+		return a.loginAttempts.BlockLogin(ctx, throttleKindCodeIP, client.IP, now.Add(delay))
+	}
+
+	return nil
+}
@@ -0,0 +1,484 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/secret"
+	"sso/internal/lib/totp"
+	"sso/internal/storage"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication not enabled")
+	ErrTwoFactorRequired    = errors.New("two-factor authentication is required for the account")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrInvalidChallenge     = errors.New("invalid two-factor challenge")
+)
+
+const (
+	// challengeSize is the number of random bytes in a two-factor challenge.
+	challengeSize = 32
+	// recoveryCodeSize is the number of random bytes in a recovery code.
+	recoveryCodeSize = 5
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorSettings configure two-factor authentication.
+type TwoFactorSettings struct {
+	// Issuer is the name authenticator apps show for the account.
+	Issuer string
+	// ChallengeTTL is how long a challenge issued on login stays valid.
+	ChallengeTTL time.Duration
+	// MaxAttempts is the number of wrong codes after which the challenge is no longer accepted.
+	MaxAttempts int
+	// RecoveryCodes is the number of recovery codes issued on enrollment.
+	RecoveryCodes int
+}
+
+type TwoFactorStorage interface {
+	TwoFactorRequired(ctx context.Context, userID int64) (bool, error)
+	SetTwoFactorRequired(ctx context.Context, userID int64, required bool) error
+	TOTP(ctx context.Context, userID int64) (models.TOTP, error)
+	SaveTOTP(ctx context.Context, userID int64, secret string) error
+	ConfirmTOTP(ctx context.Context, userID int64, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, userID int64, step int64) error
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error
+	DeleteTOTP(ctx context.Context, userID int64) error
+	SaveTwoFactorChallenge(ctx context.Context, challenge models.TwoFactorChallenge) error
+	TwoFactorChallenge(ctx context.Context, challengeHash string) (models.TwoFactorChallenge, error)
+	IncrementTwoFactorChallengeAttempts(ctx context.Context, challengeHash string) error
+	DeleteTwoFactorChallenge(ctx context.Context, challengeHash string) error
+}
+
+// EnrollTOTP generates a new authenticator app secret for the user.
+// The secret is used only after ConfirmTOTP with a code from the app.
+// If the user already has two-factor authentication enabled, returns ErrTwoFactorEnabled.
+func (a *Auth) EnrollTOTP(ctx context.Context, userID int64) (models.TOTPEnrollment, error) {
+	const op = "Auth.EnrollTOTP"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("uid", userID),
+	)
+
+	user, err := a.usrProvider.UserByID(ctx, userID)
+	if err != nil {
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	enrollment, err := a.enrollTOTP(ctx, user)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPExists) {
+			return models.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, ErrTwoFactorEnabled)
+		}
+
+		log.Error("failed to save totp", sl.Err(err))
+
+		return models.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("totp enrollment started")
+
+	return enrollment, nil
+}
+
+// ConfirmTOTP enables two-factor authentication of the user if the code matches the secret from EnrollTOTP.
+// Returns recovery codes, which are shown to the user only once.
+func (a *Auth) ConfirmTOTP(ctx context.Context, userID int64, code string) ([]string, error) {
+	const op = "Auth.ConfirmTOTP"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("uid", userID),
+	)
+
+	totpSecret, err := a.twoFactorStorage.TOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrTwoFactorNotEnabled)
+		}
+
+		log.Error("failed to get totp", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if totpSecret.Confirmed {
+		return nil, fmt.Errorf("%s: %w", op, ErrTwoFactorEnabled)
+	}
+
+	recoveryCodes, err := a.confirmTOTP(ctx, totpSecret, code)
+	if err != nil {
+		if !errors.Is(err, ErrInvalidTwoFactorCode) {
+			log.Error("failed to confirm totp", sl.Err(err))
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("two-factor authentication enabled")
+
+	return recoveryCodes, nil
+}
+
+// DisableTOTP disables two-factor authentication of the user. The code must be a valid code
+// from the authenticator app or an unused recovery code.
+// If two-factor authentication is required for the user, returns ErrTwoFactorRequired.
+func (a *Auth) DisableTOTP(ctx context.Context, userID int64, code string) error {
+	const op = "Auth.DisableTOTP"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("uid", userID),
+	)
+
+	required, err := a.twoFactorStorage.TwoFactorRequired(ctx, userID)
+	if err != nil {
+		log.Error("failed to check if two-factor authentication is required", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if required {
+		return fmt.Errorf("%s: %w", op, ErrTwoFactorRequired)
+	}
+
+	totpSecret, err := a.twoFactorStorage.TOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrTwoFactorNotEnabled)
+		}
+
+		log.Error("failed to get totp", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if totpSecret.Confirmed {
+		if err := a.checkSecondFactor(ctx, totpSecret, code); err != nil {
+			if !errors.Is(err, ErrInvalidTwoFactorCode) {
+				log.Error("failed to check code", sl.Err(err))
+			}
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := a.twoFactorStorage.DeleteTOTP(ctx, userID); err != nil {
+		log.Error("failed to delete totp", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("two-factor authentication disabled")
+
+	return nil
+}
+
+// RequireTwoFactor sets whether the user must pass two-factor authentication to log in.
+// A user that is required to but has not enabled it yet can't log in until enabling it
+// in a session started before.
+// If there is no such user, returns ErrUserNotFound.
+func (a *Auth) RequireTwoFactor(ctx context.Context, userID int64, required bool) error {
+	const op = "Auth.RequireTwoFactor"
+
+	if err := a.twoFactorStorage.SetTwoFactorRequired(ctx, userID, required); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.log.Info("two-factor requirement changed",
+		slog.String("op", op),
+		slog.Int64("uid", userID),
+		slog.Bool("required", required),
+	)
+
+	return nil
+}
+
+// VerifyTwoFactor exchanges the challenge returned by Login and a code from the authenticator app
+// or a recovery code for tokens. The session is started on the client the code is entered on.
+// Wrong codes count as failed logins of the user's phone and of the client, so guessing codes
+// is throttled like guessing passwords.
+func (a *Auth) VerifyTwoFactor(
+	ctx context.Context,
+	challenge string,
+	code string,
+	client models.ClientInfo,
+) (models.TokenPair, error) {
+	const op = "Auth.VerifyTwoFactor"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("ip", client.IP),
+	)
+
+	challengeHash := secret.Hash(challenge)
+
+	stored, err := a.twoFactorStorage.TwoFactorChallenge(ctx, challengeHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrChallengeNotFound) {
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+		}
+
+		log.Error("failed to get challenge", sl.Err(err))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("uid", stored.UserID))
+
+	if time.Now().After(stored.ExpiresAt) || stored.Attempts >= a.twoFactor.MaxAttempts {
+		log.Info("challenge expired or attempts exhausted")
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+	}
+
+	user, err := a.usrProvider.UserByID(ctx, stored.UserID)
+	if err != nil {
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.checkLoginAllowed(ctx, user.Phone, client); err != nil {
+		var retryErr *RetryAfterError
+		if errors.As(err, &retryErr) {
+			log.Warn("two-factor verification blocked", sl.Err(err))
+			a.audit(ctx, models.AuditEvent{
+				Event:   models.AuditEventTwoFactor,
+				UserID:  user.ID,
+				Phone:   user.Phone,
+				Outcome: models.AuditOutcomeFailure,
+				Reason:  "too_many_attempts",
+			}, client)
+		} else {
+			log.Error("failed to check login attempts", sl.Err(err))
+		}
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	totpSecret, err := a.twoFactorStorage.TOTP(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			log.Warn("totp disabled after the challenge was issued")
+
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+		}
+
+		log.Error("failed to get totp", sl.Err(err))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !totpSecret.Confirmed {
+		log.Warn("totp re-enrolled after the challenge was issued")
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+	}
+
+	if err := a.checkSecondFactor(ctx, totpSecret, code); err != nil {
+		if !errors.Is(err, ErrInvalidTwoFactorCode) {
+			log.Error("failed to check code", sl.Err(err))
+
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Info("invalid two-factor code")
+		a.audit(ctx, models.AuditEvent{
+			Event:   models.AuditEventTwoFactor,
+			UserID:  user.ID,
+			Phone:   user.Phone,
+			Outcome: models.AuditOutcomeFailure,
+			Reason:  "invalid_code",
+		}, client)
+
+		if err := a.twoFactorStorage.IncrementTwoFactorChallengeAttempts(ctx, challengeHash); err != nil {
+			log.Error("failed to count attempt", sl.Err(err))
+
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := a.recordLoginFailure(ctx, user.Phone, client); err != nil {
+			log.Error("failed to record login attempt", sl.Err(err))
+
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidTwoFactorCode)
+	}
+
+	if err := a.twoFactorStorage.DeleteTwoFactorChallenge(ctx, challengeHash); err != nil {
+		if errors.Is(err, storage.ErrChallengeNotFound) {
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+		}
+
+		log.Error("failed to delete challenge", sl.Err(err))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.loginAttempts.ResetLoginFailures(ctx, throttleKindPhone, user.Phone); err != nil {
+		log.Error("failed to reset login attempts", sl.Err(err))
+
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, err := a.app(ctx, stored.AppID)
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens, err := a.signIn(ctx, user, app, client)
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user passed two-factor authentication")
+	a.audit(ctx, models.AuditEvent{
+		Event:   models.AuditEventTwoFactor,
+		UserID:  user.ID,
+		Phone:   user.Phone,
+		Outcome: models.AuditOutcomeSuccess,
+	}, client)
+
+	return tokens, nil
+}
+
+// secondFactor returns a two-factor challenge if the user has two-factor authentication enabled.
+// Returns false if the second factor is not needed, and ErrTwoFactorRequired if the user is required
+// to pass it but has not enabled it yet.
+func (a *Auth) secondFactor(ctx context.Context, user models.User, app models.App) (models.LoginResult, bool, error) {
+	required, err := a.twoFactorStorage.TwoFactorRequired(ctx, user.ID)
+	if err != nil {
+		return models.LoginResult{}, false, err
+	}
+
+	totpSecret, err := a.twoFactorStorage.TOTP(ctx, user.ID)
+	switch {
+	case err == nil && totpSecret.Confirmed:
+	case err != nil && !errors.Is(err, storage.ErrTOTPNotFound):
+		return models.LoginResult{}, false, err
+	case required:
+		return models.LoginResult{}, false, ErrTwoFactorRequired
+	default:
+		return models.LoginResult{}, false, nil
+	}
+
+	challenge, err := secret.Generate(challengeSize)
+	if err != nil {
+		return models.LoginResult{}, false, err
+	}
+
+	err = a.twoFactorStorage.SaveTwoFactorChallenge(ctx, models.TwoFactorChallenge{
+		Hash:      secret.Hash(challenge),
+		UserID:    user.ID,
+		AppID:     app.ID,
+		ExpiresAt: time.Now().Add(a.twoFactor.ChallengeTTL),
+	})
+	if err != nil {
+		return models.LoginResult{}, false, err
+	}
+
+	return models.LoginResult{TwoFactorChallenge: challenge}, true, nil
+}
+
+// enrollTOTP saves a new unconfirmed secret of the user.
+func (a *Auth) enrollTOTP(ctx context.Context, user models.User) (models.TOTPEnrollment, error) {
+	totpSecret, err := totp.GenerateSecret()
+	if err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+
+	if err := a.twoFactorStorage.SaveTOTP(ctx, user.ID, totpSecret); err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+
+	return models.TOTPEnrollment{
+		Secret: totpSecret,
+		URI:    totp.ProvisioningURI(totpSecret, a.twoFactor.Issuer, user.Phone),
+	}, nil
+}
+
+// confirmTOTP confirms the unconfirmed secret with the code and returns new recovery codes.
+func (a *Auth) confirmTOTP(ctx context.Context, totpSecret models.TOTP, code string) ([]string, error) {
+	step, ok := totp.Validate(totpSecret.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes := make([]string, a.twoFactor.RecoveryCodes)
+	hashes := make([]string, a.twoFactor.RecoveryCodes)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		codes[i] = code
+		hashes[i] = recoveryCodeHash(code)
+	}
+
+	if err := a.twoFactorStorage.ConfirmTOTP(ctx, totpSecret.UserID, step, hashes); err != nil {
+		if errors.Is(err, storage.ErrTOTPExists) {
+			return nil, ErrTwoFactorEnabled
+		}
+
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// checkSecondFactor accepts a code from the authenticator app or an unused recovery code.
+// Every code is accepted only once.
+func (a *Auth) checkSecondFactor(ctx context.Context, totpSecret models.TOTP, code string) error {
+	if step, ok := totp.Validate(totpSecret.Secret, code, time.Now()); ok {
+		err := a.twoFactorStorage.UseTOTPStep(ctx, totpSecret.UserID, step)
+		if errors.Is(err, storage.ErrTOTPStepUsed) {
+			return ErrInvalidTwoFactorCode
+		}
+
+		return err
+	}
+
+	err := a.twoFactorStorage.UseRecoveryCode(ctx, totpSecret.UserID, recoveryCodeHash(code))
+	if errors.Is(err, storage.ErrRecoveryCodeNotFound) {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return err
+}
+
+// newRecoveryCode returns a random recovery code like abcd-efgh.
+func newRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+
+	return code[:4] + "-" + code[4:], nil
+}
+
+// recoveryCodeHash returns the hash of the recovery code, ignoring case and dashes.
+func recoveryCodeHash(code string) string {
+	return secret.Hash(strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", "")))
+}
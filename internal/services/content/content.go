@@ -0,0 +1,385 @@
+package content
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/storage"
+	"strings"
+)
+
+var (
+	ErrInvalidContent = errors.New("invalid content")
+	ErrNotFound       = errors.New("content not found")
+	// ErrClipNotFound means that the clip an author, a poet, an article or a quiz refers to doesn't exist.
+	ErrClipNotFound = errors.New("clip not found")
+	ErrInUse        = errors.New("content is in use")
+	ErrQuizExists   = errors.New("quiz already exists")
+)
+
+type Storage interface {
+	SaveAuthor(ctx context.Context, author models.Author) (models.Author, error)
+	UpdateAuthor(ctx context.Context, author models.Author) (models.Author, error)
+	DeleteAuthor(ctx context.Context, id int64) error
+	SavePoet(ctx context.Context, poet models.Poet) (models.Poet, error)
+	UpdatePoet(ctx context.Context, poet models.Poet) (models.Poet, error)
+	DeletePoet(ctx context.Context, id int64) error
+	SaveArticle(ctx context.Context, article models.Article) (models.Article, error)
+	UpdateArticle(ctx context.Context, article models.Article) (models.Article, error)
+	DeleteArticle(ctx context.Context, id int64) error
+	SaveClip(ctx context.Context, clip models.Clip) (models.Clip, error)
+	UpdateClip(ctx context.Context, clip models.Clip) (models.Clip, error)
+	DeleteClip(ctx context.Context, id int64) error
+	SaveQuiz(ctx context.Context, quiz models.Quiz) (models.Quiz, error)
+	UpdateQuiz(ctx context.Context, quiz models.Quiz) (models.Quiz, error)
+	DeleteQuiz(ctx context.Context, id int64) error
+}
+
+// Content manages the catalog of authors, poets and articles and the clips and quizzes they refer to.
+type Content struct {
+	log     *slog.Logger
+	storage Storage
+}
+
+func New(log *slog.Logger, storage Storage) *Content {
+	return &Content{
+		log:     log,
+		storage: storage,
+	}
+}
+
+// CreateAuthor validates and saves a new author and returns the stored one.
+func (c *Content) CreateAuthor(ctx context.Context, author models.Author) (models.Author, error) {
+	const op = "Content.CreateAuthor"
+
+	if err := validateEntry(&author.Name, &author.Image, author.Clip); err != nil {
+		return models.Author{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := c.storage.SaveAuthor(ctx, author)
+	if err != nil {
+		return models.Author{}, c.storageError(op, err)
+	}
+
+	c.log.Info("author created", slog.String("op", op), slog.Int64("author_id", saved.ID))
+
+	return saved, nil
+}
+
+// UpdateAuthor validates and replaces the author and returns the stored one.
+func (c *Content) UpdateAuthor(ctx context.Context, author models.Author) (models.Author, error) {
+	const op = "Content.UpdateAuthor"
+
+	if err := validateEntry(&author.Name, &author.Image, author.Clip); err != nil {
+		return models.Author{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := c.storage.UpdateAuthor(ctx, author)
+	if err != nil {
+		return models.Author{}, c.storageError(op, err)
+	}
+
+	c.log.Info("author updated", slog.String("op", op), slog.Int64("author_id", saved.ID))
+
+	return saved, nil
+}
+
+// DeleteAuthor deletes the author, also from favorites of all users.
+func (c *Content) DeleteAuthor(ctx context.Context, id int64) error {
+	const op = "Content.DeleteAuthor"
+
+	if err := c.storage.DeleteAuthor(ctx, id); err != nil {
+		return c.storageError(op, err)
+	}
+
+	c.log.Info("author deleted", slog.String("op", op), slog.Int64("author_id", id))
+
+	return nil
+}
+
+// CreatePoet validates and saves a new poet and returns the stored one.
+func (c *Content) CreatePoet(ctx context.Context, poet models.Poet) (models.Poet, error) {
+	const op = "Content.CreatePoet"
+
+	if err := validateEntry(&poet.Name, &poet.Image, poet.Clip); err != nil {
+		return models.Poet{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := c.storage.SavePoet(ctx, poet)
+	if err != nil {
+		return models.Poet{}, c.storageError(op, err)
+	}
+
+	c.log.Info("poet created", slog.String("op", op), slog.Int64("poet_id", saved.ID))
+
+	return saved, nil
+}
+
+// UpdatePoet validates and replaces the poet and returns the stored one.
+func (c *Content) UpdatePoet(ctx context.Context, poet models.Poet) (models.Poet, error) {
+	const op = "Content.UpdatePoet"
+
+	if err := validateEntry(&poet.Name, &poet.Image, poet.Clip); err != nil {
+		return models.Poet{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := c.storage.UpdatePoet(ctx, poet)
+	if err != nil {
+		return models.Poet{}, c.storageError(op, err)
+	}
+
+	c.log.Info("poet updated", slog.String("op", op), slog.Int64("poet_id", saved.ID))
+
+	return saved, nil
+}
+
+// DeletePoet deletes the poet, also from favorites of all users.
+func (c *Content) DeletePoet(ctx context.Context, id int64) error {
+	const op = "Content.DeletePoet"
+
+	if err := c.storage.DeletePoet(ctx, id); err != nil {
+		return c.storageError(op, err)
+	}
+
+	c.log.Info("poet deleted", slog.String("op", op), slog.Int64("poet_id", id))
+
+	return nil
+}
+
+// CreateArticle validates and saves a new article and returns the stored one.
+func (c *Content) CreateArticle(ctx context.Context, article models.Article) (models.Article, error) {
+	const op = "Content.CreateArticle"
+
+	if err := validateEntry(&article.Name, &article.Image, article.Clip); err != nil {
+		return models.Article{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := c.storage.SaveArticle(ctx, article)
+	if err != nil {
+		return models.Article{}, c.storageError(op, err)
+	}
+
+	c.log.Info("article created", slog.String("op", op), slog.Int64("article_id", saved.ID))
+
+	return saved, nil
+}
+
+// UpdateArticle validates and replaces the article and returns the stored one.
+func (c *Content) UpdateArticle(ctx context.Context, article models.Article) (models.Article, error) {
+	const op = "Content.UpdateArticle"
+
+	if err := validateEntry(&article.Name, &article.Image, article.Clip); err != nil {
+		return models.Article{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := c.storage.UpdateArticle(ctx, article)
+	if err != nil {
+		return models.Article{}, c.storageError(op, err)
+	}
+
+	c.log.Info("article updated", slog.String("op", op), slog.Int64("article_id", saved.ID))
+
+	return saved, nil
+}
+
+// DeleteArticle deletes the article, also from favorites of all users.
+func (c *Content) DeleteArticle(ctx context.Context, id int64) error {
+	const op = "Content.DeleteArticle"
+
+	if err := c.storage.DeleteArticle(ctx, id); err != nil {
+		return c.storageError(op, err)
+	}
+
+	c.log.Info("article deleted", slog.String("op", op), slog.Int64("article_id", id))
+
+	return nil
+}
+
+// CreateClip validates and saves a new clip with its text and returns the stored one.
+// The quiz of the clip is created separately, with the ID of the clip.
+func (c *Content) CreateClip(ctx context.Context, clip models.Clip) (models.Clip, error) {
+	const op = "Content.CreateClip"
+
+	if err := validateClip(&clip); err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := c.storage.SaveClip(ctx, clip)
+	if err != nil {
+		return models.Clip{}, c.storageError(op, err)
+	}
+
+	c.log.Info("clip created", slog.String("op", op), slog.Int64("clip_id", saved.ID))
+
+	return saved, nil
+}
+
+// UpdateClip validates and replaces the image and the text of the clip and returns the stored clip.
+func (c *Content) UpdateClip(ctx context.Context, clip models.Clip) (models.Clip, error) {
+	const op = "Content.UpdateClip"
+
+	if err := validateClip(&clip); err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := c.storage.UpdateClip(ctx, clip)
+	if err != nil {
+		return models.Clip{}, c.storageError(op, err)
+	}
+
+	c.log.Info("clip updated", slog.String("op", op), slog.Int64("clip_id", saved.ID))
+
+	return saved, nil
+}
+
+// DeleteClip deletes the clip with its quiz. A clip that an author, a poet or an article refers to
+// can't be deleted, ErrInUse is returned.
+func (c *Content) DeleteClip(ctx context.Context, id int64) error {
+	const op = "Content.DeleteClip"
+
+	if err := c.storage.DeleteClip(ctx, id); err != nil {
+		return c.storageError(op, err)
+	}
+
+	c.log.Info("clip deleted", slog.String("op", op), slog.Int64("clip_id", id))
+
+	return nil
+}
+
+// CreateQuiz validates and saves the quiz of the clip whose ID the quiz has and returns the stored quiz.
+func (c *Content) CreateQuiz(ctx context.Context, quiz models.Quiz) (models.Quiz, error) {
+	const op = "Content.CreateQuiz"
+
+	if quiz.ID <= 0 {
+		return models.Quiz{}, fmt.Errorf("%s: %w: id of the clip is required", op, ErrInvalidContent)
+	}
+
+	if err := validateQuiz(&quiz); err != nil {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := c.storage.SaveQuiz(ctx, quiz)
+	if err != nil {
+		return models.Quiz{}, c.storageError(op, err)
+	}
+
+	c.log.Info("quiz created", slog.String("op", op), slog.Int64("quiz_id", saved.ID))
+
+	return saved, nil
+}
+
+// UpdateQuiz validates and replaces the quiz with its answers and returns the stored quiz.
+func (c *Content) UpdateQuiz(ctx context.Context, quiz models.Quiz) (models.Quiz, error) {
+	const op = "Content.UpdateQuiz"
+
+	if err := validateQuiz(&quiz); err != nil {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := c.storage.UpdateQuiz(ctx, quiz)
+	if err != nil {
+		return models.Quiz{}, c.storageError(op, err)
+	}
+
+	c.log.Info("quiz updated", slog.String("op", op), slog.Int64("quiz_id", saved.ID))
+
+	return saved, nil
+}
+
+// DeleteQuiz deletes the quiz with its answers.
+func (c *Content) DeleteQuiz(ctx context.Context, id int64) error {
+	const op = "Content.DeleteQuiz"
+
+	if err := c.storage.DeleteQuiz(ctx, id); err != nil {
+		return c.storageError(op, err)
+	}
+
+	c.log.Info("quiz deleted", slog.String("op", op), slog.Int64("quiz_id", id))
+
+	return nil
+}
+
+// storageError translates the storage error to the errors of the service, logging unexpected ones.
+func (c *Content) storageError(op string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrContentNotFound):
+		return fmt.Errorf("%s: %w", op, ErrNotFound)
+	case errors.Is(err, storage.ErrClipNotFound):
+		return fmt.Errorf("%s: %w", op, ErrClipNotFound)
+	case errors.Is(err, storage.ErrContentInUse):
+		return fmt.Errorf("%s: %w", op, ErrInUse)
+	case errors.Is(err, storage.ErrQuizExists):
+		return fmt.Errorf("%s: %w", op, ErrQuizExists)
+	}
+
+	c.log.Error("failed to write content", slog.String("op", op), sl.Err(err))
+
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+// validateEntry trims the name and the image of a catalog entry and checks that the entry
+// has a name and refers to a clip.
+func validateEntry(name *string, image *string, clip int64) error {
+	*name = strings.TrimSpace(*name)
+	*image = strings.TrimSpace(*image)
+
+	switch {
+	case *name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidContent)
+	case clip <= 0:
+		return fmt.Errorf("%w: clip is required", ErrInvalidContent)
+	}
+
+	return nil
+}
+
+// validateClip checks that the clip has an image and at least one text section, each with text.
+func validateClip(clip *models.Clip) error {
+	clip.Image = strings.TrimSpace(clip.Image)
+
+	if clip.Image == "" {
+		return fmt.Errorf("%w: image is required", ErrInvalidContent)
+	}
+	if len(clip.Text) == 0 {
+		return fmt.Errorf("%w: text is required", ErrInvalidContent)
+	}
+
+	for i := range clip.Text {
+		clip.Text[i].Title = strings.TrimSpace(clip.Text[i].Title)
+		if strings.TrimSpace(clip.Text[i].Text) == "" {
+			return fmt.Errorf("%w: text section %d is empty", ErrInvalidContent, i+1)
+		}
+	}
+
+	return nil
+}
+
+// validateQuiz checks that the quiz has a question and at least two answers with text, at least one of them right.
+func validateQuiz(quiz *models.Quiz) error {
+	quiz.Question = strings.TrimSpace(quiz.Question)
+
+	if quiz.Question == "" {
+		return fmt.Errorf("%w: question is required", ErrInvalidContent)
+	}
+	if len(quiz.Answers) < 2 {
+		return fmt.Errorf("%w: at least two answers are required", ErrInvalidContent)
+	}
+
+	right := false
+	for i := range quiz.Answers {
+		quiz.Answers[i].Text = strings.TrimSpace(quiz.Answers[i].Text)
+		if quiz.Answers[i].Text == "" {
+			return fmt.Errorf("%w: answer %d is empty", ErrInvalidContent, i+1)
+		}
+
+		right = right || quiz.Answers[i].IsRight
+	}
+	if !right {
+		return fmt.Errorf("%w: at least one answer must be right", ErrInvalidContent)
+	}
+
+	return nil
+}
@@ -3,32 +3,41 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"sso/internal/domain/models"
+	"sso/internal/lib/clientinfo"
+	"sso/internal/lib/cursor"
+	"sso/internal/storage"
 	"strconv"
 	"time"
 )
 
+const (
+	// defaultCatalogLimit is the page size of the catalog if the request doesn't set one.
+	defaultCatalogLimit = 50
+	// maxCatalogLimit is the largest page size of the catalog.
+	maxCatalogLimit = 200
+)
+
 type UserProvider interface {
-	DeleteUser(ctx context.Context, userId int64) error
+	SoftDeleteUser(ctx context.Context, userID int64, deletedAt time.Time) error
+	RestoreUser(ctx context.Context, userID int64) error
 	GetUser(ctx context.Context, userId int64) (models.UserData, error)
 }
 
 type PoetProvider interface {
-	Poets(ctx context.Context, userId int64) ([]models.Poet, error)
-	UpdatePoetIsFave(ctx context.Context, userID int64, poetID int64) error
+	Poets(ctx context.Context, userId int64, filter models.CatalogFilter) ([]models.Poet, error)
 }
 
 type ArticleProvider interface {
-	Articles(ctx context.Context, userId int64) ([]models.Article, error)
-	UpdateArticleIsFave(ctx context.Context, userID int64, articleID int64) error
+	Articles(ctx context.Context, userId int64, filter models.CatalogFilter) ([]models.Article, error)
 }
 
 type AuthorProvider interface {
-	Authors(ctx context.Context, userId int64) ([]models.Author, error)
-	UpdateAuthorIsFave(ctx context.Context, userID int64, authorID int64) error
+	Authors(ctx context.Context, userId int64, filter models.CatalogFilter) ([]models.Author, error)
 }
 
 type ClipProvider interface {
@@ -39,15 +48,31 @@ type QuizProvider interface {
 	GetQuiz(ctx context.Context, quizId int64) (models.Quiz, error)
 }
 
+type TokenRevoker interface {
+	RevokeUserTokens(ctx context.Context, userID int64) error
+}
+
+type Auditor interface {
+	Record(ctx context.Context, event models.AuditEvent)
+}
+
+// UserCache caches users for introspection. A user is dropped from it when their account is deleted or restored.
+type UserCache interface {
+	ForgetUser(userID int64)
+}
+
 type Core struct {
-	log             *slog.Logger
-	userProvider    UserProvider
-	quizProvider    QuizProvider
-	clipProvider    ClipProvider
-	poetProvider    PoetProvider
-	articleProvider ArticleProvider
-	authorProvider  AuthorProvider
-	tokenTTL        time.Duration
+	log                 *slog.Logger
+	userProvider        UserProvider
+	quizProvider        QuizProvider
+	clipProvider        ClipProvider
+	poetProvider        PoetProvider
+	articleProvider     ArticleProvider
+	authorProvider      AuthorProvider
+	tokenRevoker        TokenRevoker
+	userCache           UserCache
+	auditor             Auditor
+	deletionGracePeriod time.Duration
 }
 
 func New(
@@ -58,20 +83,27 @@ func New(
 	poetProvider PoetProvider,
 	articleProvider ArticleProvider,
 	authorProvider AuthorProvider,
-	tokenTTL time.Duration,
+	tokenRevoker TokenRevoker,
+	userCache UserCache,
+	auditor Auditor,
+	deletionGracePeriod time.Duration,
 ) *Core {
 	return &Core{
-		log:             log,
-		userProvider:    userProvider,
-		quizProvider:    quizProvider,
-		clipProvider:    clipProvider,
-		poetProvider:    poetProvider,
-		articleProvider: articleProvider,
-		authorProvider:  authorProvider,
-		tokenTTL:        tokenTTL,
+		log:                 log,
+		userProvider:        userProvider,
+		quizProvider:        quizProvider,
+		clipProvider:        clipProvider,
+		poetProvider:        poetProvider,
+		articleProvider:     articleProvider,
+		authorProvider:      authorProvider,
+		tokenRevoker:        tokenRevoker,
+		userCache:           userCache,
+		auditor:             auditor,
+		deletionGracePeriod: deletionGracePeriod,
 	}
 }
 
+// GetAuthorHandler handles the HTTP GET request for a page of authors, see catalogFilter for the parameters.
 func (c *Core) GetAuthorHandler(w http.ResponseWriter, r *http.Request) {
 	const op = "core.GetAuthorHandler"
 
@@ -81,19 +113,22 @@ func (c *Core) GetAuthorHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	authors, err := c.authorProvider.Authors(r.Context(), uid)
+	filter, err := catalogFilter(r)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(authors); err != nil {
+	authors, err := c.authorProvider.Authors(r.Context(), uid, filter)
+	if err != nil {
 		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
 		return
 	}
+
+	writeCatalogPage(w, op, filter, authors, func(a models.Author) (string, int64) { return a.Name, a.ID })
 }
 
+// GetArticlesHandler handles the HTTP GET request for a page of articles, see catalogFilter for the parameters.
 func (c *Core) GetArticlesHandler(w http.ResponseWriter, r *http.Request) {
 	const op = "core.GetArticlesHandler"
 
@@ -103,19 +138,22 @@ func (c *Core) GetArticlesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	articles, err := c.articleProvider.Articles(r.Context(), uid)
+	filter, err := catalogFilter(r)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(articles); err != nil {
+	articles, err := c.articleProvider.Articles(r.Context(), uid, filter)
+	if err != nil {
 		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
 		return
 	}
+
+	writeCatalogPage(w, op, filter, articles, func(a models.Article) (string, int64) { return a.Name, a.ID })
 }
 
+// GetPoetsHandler handles the HTTP GET request for a page of poets, see catalogFilter for the parameters.
 func (c *Core) GetPoetsHandler(w http.ResponseWriter, r *http.Request) {
 	const op = "core.GetPoetsHandler"
 
@@ -125,66 +163,121 @@ func (c *Core) GetPoetsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	poets, err := c.poetProvider.Poets(r.Context(), uid)
+	filter, err := catalogFilter(r)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(poets); err != nil {
+	poets, err := c.poetProvider.Poets(r.Context(), uid, filter)
+	if err != nil {
 		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
 		return
 	}
+
+	writeCatalogPage(w, op, filter, poets, func(p models.Poet) (string, int64) { return p.Name, p.ID })
 }
 
-func (c *Core) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
-	const op = "core.DeleteUserHandler"
+// catalogPage is a page of authors, poets or articles.
+type catalogPage[T any] struct {
+	Items []T `json:"items"`
+	// NextCursor is the cursor parameter of the next page. It is omitted on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
 
-	uid, ok := r.Context().Value("uid").(int64)
-	if !ok {
-		http.Error(w, "UID not found in context", http.StatusInternalServerError)
-		return
+// catalogFilter parses the query parameters of a catalog request: sort (id or name), order (asc or desc),
+// isFave (true or false), limit and cursor. Without limit and cursor the catalog is not paginated
+// and the filter has no limit. A cursor of a page in another order is invalid.
+func catalogFilter(r *http.Request) (models.CatalogFilter, error) {
+	q := r.URL.Query()
+
+	var filter models.CatalogFilter
+	if q.Has("limit") || q.Has("cursor") {
+		filter.Limit = defaultCatalogLimit
 	}
 
-	err := c.userProvider.DeleteUser(r.Context(), uid)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
-		return
+	switch q.Get("sort") {
+	case "", "id":
+	case "name":
+		filter.SortByName = true
+	default:
+		return models.CatalogFilter{}, errors.New("sort must be id or name")
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		return models.CatalogFilter{}, errors.New("order must be asc or desc")
+	}
 
-// UpdateAuthorIsFaveHandler handles the HTTP PATCH request to update the isFave field for an author.
-func (c *Core) UpdateAuthorIsFaveHandler(w http.ResponseWriter, r *http.Request) {
-	const op = "core.UpdateAuthorIsFaveHandler"
+	if v := q.Get("isFave"); v != "" {
+		isFave, err := strconv.ParseBool(v)
+		if err != nil {
+			return models.CatalogFilter{}, errors.New("invalid isFave")
+		}
+		filter.IsFave = &isFave
+	}
 
-	uid, ok := r.Context().Value("uid").(int64)
-	if !ok {
-		http.Error(w, "UID not found in context", http.StatusInternalServerError)
-		return
+	if q.Has("limit") {
+		n, err := strconv.Atoi(q.Get("limit"))
+		if err != nil || n <= 0 || n > maxCatalogLimit {
+			return models.CatalogFilter{}, fmt.Errorf("limit must be between 1 and %d", maxCatalogLimit)
+		}
+		filter.Limit = n
 	}
 
-	authorIDStr := r.URL.Query().Get("authorId")
-	authorID, err := strconv.ParseInt(authorIDStr, 10, 64)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusBadRequest)
-		return
+	if v := q.Get("cursor"); v != "" {
+		var after models.CatalogPosition
+		if err := cursor.Decode(v, &after); err != nil {
+			return models.CatalogFilter{}, err
+		}
+		if after.SortByName != filter.SortByName || after.Descending != filter.Descending {
+			return models.CatalogFilter{}, cursor.ErrInvalidCursor
+		}
+		filter.After = &after
 	}
 
-	err = c.authorProvider.UpdateAuthorIsFave(r.Context(), uid, authorID)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
-		return
+	return filter, nil
+}
+
+// writeCatalogPage responds with the page of entries and, if the page is full, the cursor of the next one
+// pointing at the last entry. position returns the name and the ID of an entry.
+// A catalog requested without pagination is written as a bare array, as it was before pagination was added.
+func writeCatalogPage[T any](
+	w http.ResponseWriter,
+	op string,
+	filter models.CatalogFilter,
+	items []T,
+	position func(item T) (string, int64),
+) {
+	var body any = items
+	if filter.Limit > 0 {
+		page := catalogPage[T]{Items: items}
+		if len(items) == filter.Limit {
+			name, id := position(items[len(items)-1])
+
+			last := models.CatalogPosition{SortByName: filter.SortByName, Descending: filter.Descending, ID: id}
+			if filter.SortByName {
+				last.Name = name
+			}
+			page.NextCursor = cursor.Encode(last)
+		}
+		body = page
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
+		return
+	}
 }
 
-// UpdateArticleIsFaveHandler handles the HTTP PATCH request to update the isFave field for an author.
-func (c *Core) UpdateArticleIsFaveHandler(w http.ResponseWriter, r *http.Request) {
-	const op = "core.UpdateArticleIsFaveHandler"
+// DeleteUserHandler schedules the deletion of the user's account. The account is purged after the grace period
+// unless the user logs in again and restores it. All tokens of the user are revoked right away.
+func (c *Core) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "core.DeleteUserHandler"
 
 	uid, ok := r.Context().Value("uid").(int64)
 	if !ok {
@@ -192,25 +285,36 @@ func (c *Core) UpdateArticleIsFaveHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	authorIDStr := r.URL.Query().Get("articleId")
-	articleID, err := strconv.ParseInt(authorIDStr, 10, 64)
+	err := c.userProvider.SoftDeleteUser(r.Context(), uid, time.Now())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
 		return
 	}
+	c.userCache.ForgetUser(uid)
 
-	err = c.articleProvider.UpdateArticleIsFave(r.Context(), uid, articleID)
+	// Tokens already issued to the user stay valid until they expire, so they must be revoked explicitly.
+	err = c.tokenRevoker.RevokeUserTokens(r.Context(), uid)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	client := clientinfo.FromRequest(r)
+	c.auditor.Record(r.Context(), models.AuditEvent{
+		Event:     models.AuditEventUserDelete,
+		UserID:    uid,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Outcome:   models.AuditOutcomeSuccess,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// UpdatePoetIsFaveHandler handles the HTTP PATCH request to update the isFave field for an author.
-func (c *Core) UpdatePoetIsFaveHandler(w http.ResponseWriter, r *http.Request) {
-	const op = "core.UpdatePoetIsFaveHandler"
+// RestoreUserHandler cancels the scheduled deletion of the user's account.
+// Responds with 409 Conflict if the account is not pending deletion.
+func (c *Core) RestoreUserHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "core.RestoreUserHandler"
 
 	uid, ok := r.Context().Value("uid").(int64)
 	if !ok {
@@ -218,20 +322,28 @@ func (c *Core) UpdatePoetIsFaveHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	poetIDStr := r.URL.Query().Get("poetId")
-	poetID, err := strconv.ParseInt(poetIDStr, 10, 64)
+	err := c.userProvider.RestoreUser(r.Context(), uid)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusBadRequest)
-		return
-	}
+		if errors.Is(err, storage.ErrUserNotDeleted) {
+			http.Error(w, "account is not pending deletion", http.StatusConflict)
+			return
+		}
 
-	err = c.poetProvider.UpdatePoetIsFave(r.Context(), uid, poetID)
-	if err != nil {
 		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
 		return
 	}
+	c.userCache.ForgetUser(uid)
 
-	w.Header().Set("Content-Type", "application/json")
+	client := clientinfo.FromRequest(r)
+	c.auditor.Record(r.Context(), models.AuditEvent{
+		Event:     models.AuditEventUserRestore,
+		UserID:    uid,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Outcome:   models.AuditOutcomeSuccess,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (c *Core) GetClipHandler(w http.ResponseWriter, r *http.Request) {
@@ -296,6 +408,11 @@ func (c *Core) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if clips.DeletedAt != nil {
+		purgeAt := clips.DeletedAt.Add(c.deletionGracePeriod)
+		clips.PurgeAt = &purgeAt
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(clips); err != nil {
 		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
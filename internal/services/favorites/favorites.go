@@ -0,0 +1,149 @@
+package favorites
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/cursor"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/storage"
+	"time"
+)
+
+const (
+	// DefaultLimit is the page size of List if the query doesn't set one.
+	DefaultLimit = 20
+	// MaxLimit is the largest page size of List.
+	MaxLimit = 100
+)
+
+var (
+	ErrInvalidType   = errors.New("invalid content type")
+	ErrNotFound      = errors.New("content not found")
+	ErrInvalidFilter = errors.New("invalid filter")
+)
+
+type Storage interface {
+	AddFavorite(ctx context.Context, userID int64, contentType string, id int64, favoritedAt time.Time) error
+	RemoveFavorite(ctx context.Context, userID int64, contentType string, id int64) error
+	Favorites(ctx context.Context, userID int64, filter models.FavoriteFilter) ([]models.Favorite, error)
+}
+
+// Query is a request for a page of favorites of a user.
+type Query struct {
+	// Type selects favorites of one type. If empty, favorites of all types are listed.
+	Type string
+	// Order is "desc" to list the recently added favorites first, the default, or "asc".
+	Order string
+	// Cursor is the next cursor of the previous page. If empty, the first page is listed.
+	Cursor string
+	Limit  int
+}
+
+// Favorites manages the authors, poets and articles users keep in favorites.
+type Favorites struct {
+	log     *slog.Logger
+	storage Storage
+}
+
+func New(log *slog.Logger, storage Storage) *Favorites {
+	return &Favorites{
+		log:     log,
+		storage: storage,
+	}
+}
+
+// Set adds the content item to favorites of the user if fave is set and removes it from there otherwise,
+// and returns the resulting state. Repeating the call changes nothing, so clients can safely retry it.
+func (f *Favorites) Set(ctx context.Context, userID int64, contentType string, id int64, fave bool) (models.Favorite, error) {
+	const op = "Favorites.Set"
+
+	if !models.IsFavoriteType(contentType) {
+		return models.Favorite{}, fmt.Errorf("%s: %w", op, ErrInvalidType)
+	}
+
+	var err error
+	if fave {
+		err = f.storage.AddFavorite(ctx, userID, contentType, id, time.Now())
+	} else {
+		err = f.storage.RemoveFavorite(ctx, userID, contentType, id)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrContentNotFound) {
+			return models.Favorite{}, fmt.Errorf("%s: %w", op, ErrNotFound)
+		}
+
+		f.log.Error("failed to set favorite", slog.String("op", op), sl.Err(err))
+
+		return models.Favorite{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return models.Favorite{
+		Type:   contentType,
+		ID:     id,
+		IsFave: fave,
+	}, nil
+}
+
+// List returns a page of favorites of the user with the details of each item and the cursor of the next page.
+// The cursor is empty on the last page.
+func (f *Favorites) List(ctx context.Context, userID int64, query Query) ([]models.Favorite, string, error) {
+	const op = "Favorites.List"
+
+	filter := models.FavoriteFilter{
+		Type:  query.Type,
+		Limit: query.Limit,
+	}
+
+	if filter.Type != "" && !models.IsFavoriteType(filter.Type) {
+		return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidType)
+	}
+
+	switch query.Order {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		return nil, "", fmt.Errorf("%s: %w: order must be asc or desc", op, ErrInvalidFilter)
+	}
+
+	switch {
+	case filter.Limit < 0 || filter.Limit > MaxLimit:
+		return nil, "", fmt.Errorf("%s: %w: limit must be between 1 and %d", op, ErrInvalidFilter, MaxLimit)
+	case filter.Limit == 0:
+		filter.Limit = DefaultLimit
+	}
+
+	if query.Cursor != "" {
+		var after models.FavoritePosition
+		if err := cursor.Decode(query.Cursor, &after); err != nil {
+			return nil, "", fmt.Errorf("%s: %w: %w", op, ErrInvalidFilter, err)
+		}
+		if after.Ascending != filter.Ascending || !models.IsFavoriteType(after.Type) {
+			return nil, "", fmt.Errorf("%s: %w: %w", op, ErrInvalidFilter, cursor.ErrInvalidCursor)
+		}
+		filter.After = &after
+	}
+
+	favorites, err := f.storage.Favorites(ctx, userID, filter)
+	if err != nil {
+		f.log.Error("failed to get favorites", slog.String("op", op), sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var next string
+	if len(favorites) == filter.Limit {
+		last := favorites[len(favorites)-1]
+		next = cursor.Encode(models.FavoritePosition{
+			Ascending:   filter.Ascending,
+			FavoritedAt: last.FavoritedAt.Unix(),
+			Type:        last.Type,
+			ID:          last.ID,
+		})
+	}
+
+	return favorites, next, nil
+}
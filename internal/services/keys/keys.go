@@ -0,0 +1,208 @@
+package keys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/secret"
+	"sso/internal/storage"
+	"sync"
+	"time"
+)
+
+// kidSize is the number of random bytes in a key ID.
+const kidSize = 12
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+type KeyStorage interface {
+	ActiveSigningKey(ctx context.Context) (models.SigningKey, error)
+	SigningKey(ctx context.Context, kid string) (models.SigningKey, error)
+	PublishedSigningKeys(ctx context.Context) ([]models.SigningKey, error)
+	RotateSigningKey(ctx context.Context, next models.SigningKey, retiredExpireAt time.Time) error
+}
+
+type AppTTLProvider interface {
+	LongestAppTokenTTL(ctx context.Context) (time.Duration, error)
+}
+
+// Keys manages the asymmetric keys access tokens are signed with.
+type Keys struct {
+	log            *slog.Logger
+	keyStorage     KeyStorage
+	appTTLProvider AppTTLProvider
+	algorithm      string
+	rotationPeriod time.Duration
+	tokenTTL       time.Duration
+	// cipher seals private keys in the storage. If it is nil, they are stored as they are.
+	cipher *secret.Cipher
+	mu     sync.Mutex
+}
+
+func New(
+	log *slog.Logger,
+	keyStorage KeyStorage,
+	appTTLProvider AppTTLProvider,
+	algorithm string,
+	rotationPeriod time.Duration,
+	tokenTTL time.Duration,
+	cipher *secret.Cipher,
+) *Keys {
+	return &Keys{
+		log:            log,
+		keyStorage:     keyStorage,
+		appTTLProvider: appTTLProvider,
+		algorithm:      algorithm,
+		rotationPeriod: rotationPeriod,
+		tokenTTL:       tokenTTL,
+		cipher:         cipher,
+	}
+}
+
+// SigningKey returns the key to sign new tokens with.
+// If there is no active key, it is older than the rotation period or uses another algorithm,
+// a new key is generated and the previous one is retired.
+func (k *Keys) SigningKey(ctx context.Context) (models.SigningKey, error) {
+	const op = "Keys.SigningKey"
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, err := k.keyStorage.ActiveSigningKey(ctx)
+	if err != nil && !errors.Is(err, storage.ErrSigningKeyNotFound) {
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err == nil && key.Algorithm == k.algorithm && time.Since(key.CreatedAt) < k.rotationPeriod {
+		key, err = k.open(key)
+		if err != nil {
+			return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return key, nil
+	}
+
+	key, err = k.rotate(ctx)
+	if err != nil {
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// VerificationKey returns the key with the given ID if tokens signed with it may still be valid.
+func (k *Keys) VerificationKey(ctx context.Context, kid string) (models.SigningKey, error) {
+	const op = "Keys.VerificationKey"
+
+	key, err := k.keyStorage.SigningKey(ctx, kid)
+	if err != nil {
+		if errors.Is(err, storage.ErrSigningKeyNotFound) {
+			return models.SigningKey{}, fmt.Errorf("%s: %w", op, ErrUnknownKey)
+		}
+
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !key.ExpiresAt.IsZero() && time.Now().After(key.ExpiresAt) {
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, ErrUnknownKey)
+	}
+
+	key, err = k.open(key)
+	if err != nil {
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// JWKS returns public parts of all keys tokens may still be verified with.
+func (k *Keys) JWKS(ctx context.Context) (jwt.JWKS, error) {
+	const op = "Keys.JWKS"
+
+	keys, err := k.keyStorage.PublishedSigningKeys(ctx)
+	if err != nil {
+		return jwt.JWKS{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	jwks := jwt.JWKS{Keys: make([]jwt.JWK, 0, len(keys))}
+	for _, key := range keys {
+		jwk, err := jwt.PublicJWK(key)
+		if err != nil {
+			return jwt.JWKS{}, fmt.Errorf("%s: %w", op, err)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks, nil
+}
+
+// JWKSHandler handles the HTTP GET request for the public keys in JSON Web Key Set format.
+func (k *Keys) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "keys.JWKSHandler"
+
+	jwks, err := k.JWKS(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(jwks); err != nil {
+		http.Error(w, fmt.Sprintf("%s: %v", op, err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// rotate generates a new signing key. Retired keys stay published as long as
+// the longest-living token signed with them may be valid.
+func (k *Keys) rotate(ctx context.Context) (models.SigningKey, error) {
+	kid, err := secret.Generate(kidSize)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	key, err := jwt.GenerateKey(kid, k.algorithm)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	retention, err := k.appTTLProvider.LongestAppTokenTTL(ctx)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+	if retention < k.tokenTTL {
+		retention = k.tokenTTL
+	}
+
+	stored := key
+	stored.PrivateKey, err = k.cipher.Seal(key.PrivateKey, []byte(key.ID))
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	if err := k.keyStorage.RotateSigningKey(ctx, stored, time.Now().Add(retention)); err != nil {
+		return models.SigningKey{}, err
+	}
+
+	k.log.Info("signing key rotated", slog.String("kid", kid), slog.String("algorithm", k.algorithm))
+
+	return key, nil
+}
+
+// open decrypts the private key of the stored key. Keys stored before sealing was enabled are returned as they are.
+func (k *Keys) open(key models.SigningKey) (models.SigningKey, error) {
+	privateKey, err := k.cipher.Open(key.PrivateKey, []byte(key.ID))
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	key.PrivateKey = privateKey
+
+	return key, nil
+}
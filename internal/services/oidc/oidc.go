@@ -0,0 +1,584 @@
+package oidc
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/secret"
+	"sso/internal/services/auth"
+	"sso/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// codeSize is the number of random bytes in an authorization code.
+	codeSize = 32
+	// codeChallengeMethod is the only supported PKCE method.
+	codeChallengeMethod = "S256"
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+var (
+	// ErrInvalidClient is returned by the authorization endpoint if the client or its redirect URI is unknown.
+	// The user must not be redirected then.
+	ErrInvalidClient = errors.New("invalid client or redirect uri")
+)
+
+// Error is an OAuth 2.0 error. Code is one of the error codes of RFC 6749, e.g. invalid_grant.
+type Error struct {
+	Code        string
+	Description string
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+type Authenticator interface {
+	Login(
+		ctx context.Context,
+		phone string,
+		password string,
+		appID int64,
+		client models.ClientInfo,
+	) (models.LoginResult, error)
+	VerifyTwoFactor(
+		ctx context.Context,
+		challenge string,
+		code string,
+		client models.ClientInfo,
+	) (models.TokenPair, error)
+	Refresh(
+		ctx context.Context,
+		refreshToken string,
+		client models.ClientInfo,
+	) (models.TokenPair, error)
+	ValidateToken(
+		ctx context.Context,
+		accessToken string,
+	) (jwt.Claims, error)
+}
+
+type ClientStorage interface {
+	OAuthClient(ctx context.Context, id string) (models.OAuthClient, error)
+}
+
+type CodeStorage interface {
+	SaveAuthCode(ctx context.Context, code models.AuthCode) error
+	ConsumeAuthCode(ctx context.Context, codeHash string) (models.AuthCode, error)
+}
+
+type UserProvider interface {
+	UserByID(ctx context.Context, userID int64) (models.User, error)
+}
+
+type AppProvider interface {
+	App(ctx context.Context, appID int64) (models.App, error)
+}
+
+type KeyProvider interface {
+	SigningKey(ctx context.Context) (models.SigningKey, error)
+}
+
+// Settings configure the OpenID Connect provider.
+type Settings struct {
+	// Issuer is the public base URL of the provider. It is the iss claim of ID tokens
+	// and the base of the endpoints in the discovery document.
+	Issuer string
+	// CodeTTL is how long an authorization code can be exchanged for tokens.
+	CodeTTL time.Duration
+	// TokenTTL is the lifetime of access tokens of apps that don't override it. ID tokens live as long.
+	TokenTTL time.Duration
+	// SigningAlgorithm is the algorithm ID tokens are signed with.
+	SigningAlgorithm string
+}
+
+// OIDC is an OpenID Connect provider with the authorization code flow and PKCE.
+// Users sign in with their phone and password, and clients get tokens of the app they are registered for.
+type OIDC struct {
+	log           *slog.Logger
+	authenticator Authenticator
+	clientStorage ClientStorage
+	codeStorage   CodeStorage
+	userProvider  UserProvider
+	appProvider   AppProvider
+	keyProvider   KeyProvider
+	settings      Settings
+}
+
+func New(
+	log *slog.Logger,
+	authenticator Authenticator,
+	clientStorage ClientStorage,
+	codeStorage CodeStorage,
+	userProvider UserProvider,
+	appProvider AppProvider,
+	keyProvider KeyProvider,
+	settings Settings,
+) *OIDC {
+	return &OIDC{
+		log:           log,
+		authenticator: authenticator,
+		clientStorage: clientStorage,
+		codeStorage:   codeStorage,
+		userProvider:  userProvider,
+		appProvider:   appProvider,
+		keyProvider:   keyProvider,
+		settings:      settings,
+	}
+}
+
+// Discovery is the OpenID Connect discovery document.
+type Discovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+// Discovery returns the discovery document of the provider.
+func (o *OIDC) Discovery() Discovery {
+	issuer := strings.TrimSuffix(o.settings.Issuer, "/")
+
+	return Discovery{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserInfoEndpoint:                  issuer + "/oauth/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{o.settings.SigningAlgorithm},
+		ScopesSupported:                   []string{models.OAuthScopeOpenID, models.OAuthScopeProfile, models.OAuthScopePhone},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{codeChallengeMethod},
+	}
+}
+
+// CheckAuthorizeRequest checks the authorization request and returns its client.
+// If the client or the redirect URI is unknown, returns ErrInvalidClient.
+// Other problems are returned as *Error to be sent to the redirect URI.
+func (o *OIDC) CheckAuthorizeRequest(ctx context.Context, req models.AuthorizeRequest) (models.OAuthClient, error) {
+	const op = "OIDC.CheckAuthorizeRequest"
+
+	client, err := o.clientStorage.OAuthClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrOAuthClientNotFound) {
+			return models.OAuthClient{}, fmt.Errorf("%s: %w", op, ErrInvalidClient)
+		}
+
+		o.log.Error("failed to get client", slog.String("op", op), sl.Err(err))
+
+		return models.OAuthClient{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !slices.Contains(client.RedirectURIs, req.RedirectURI) {
+		return models.OAuthClient{}, fmt.Errorf("%s: %w", op, ErrInvalidClient)
+	}
+
+	switch {
+	case req.ResponseType != "code":
+		return client, &Error{Code: "unsupported_response_type", Description: "only the code response type is supported"}
+	case !slices.Contains(strings.Fields(req.Scope), models.OAuthScopeOpenID):
+		return client, &Error{Code: "invalid_scope", Description: "the openid scope is required"}
+	case req.CodeChallenge == "" || req.CodeChallengeMethod != codeChallengeMethod:
+		return client, &Error{Code: "invalid_request", Description: "PKCE with the S256 method is required"}
+	}
+
+	return client, nil
+}
+
+// AuthorizeResult is the result of signing the user in on the authorization endpoint.
+// Either Code or TwoFactorChallenge is set.
+type AuthorizeResult struct {
+	Code string
+	// TwoFactorChallenge is set if the user must pass the second factor with AuthorizeTwoFactor.
+	TwoFactorChallenge string
+}
+
+// Authorize signs the user in with the phone and password for the client of the authorization request,
+// which must have been checked with CheckAuthorizeRequest, and returns an authorization code.
+// Errors of Auth.Login are returned as is.
+func (o *OIDC) Authorize(
+	ctx context.Context,
+	req models.AuthorizeRequest,
+	phone string,
+	password string,
+	clientInfo models.ClientInfo,
+) (AuthorizeResult, error) {
+	const op = "OIDC.Authorize"
+
+	client, err := o.CheckAuthorizeRequest(ctx, req)
+	if err != nil {
+		return AuthorizeResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result, err := o.authenticator.Login(ctx, phone, password, client.AppID, clientInfo)
+	if err != nil {
+		return AuthorizeResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.TwoFactorChallenge != "" {
+		return AuthorizeResult{TwoFactorChallenge: result.TwoFactorChallenge}, nil
+	}
+
+	code, err := o.issueCode(ctx, req, result.TokenPair)
+	if err != nil {
+		return AuthorizeResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return AuthorizeResult{Code: code}, nil
+}
+
+// AuthorizeTwoFactor passes the second factor of the challenge returned by Authorize and returns an authorization code.
+// Errors of Auth.VerifyTwoFactor are returned as is.
+func (o *OIDC) AuthorizeTwoFactor(
+	ctx context.Context,
+	req models.AuthorizeRequest,
+	challenge string,
+	code string,
+	clientInfo models.ClientInfo,
+) (string, error) {
+	const op = "OIDC.AuthorizeTwoFactor"
+
+	if _, err := o.CheckAuthorizeRequest(ctx, req); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens, err := o.authenticator.VerifyTwoFactor(ctx, challenge, code, clientInfo)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	authCode, err := o.issueCode(ctx, req, tokens)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return authCode, nil
+}
+
+// Exchange handles the token request of the authorization code or refresh token grant.
+// Failures are returned as *Error.
+func (o *OIDC) Exchange(ctx context.Context, req models.TokenRequest, clientInfo models.ClientInfo) (models.OAuthTokens, error) {
+	const op = "OIDC.Exchange"
+
+	client, err := o.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var tokens models.OAuthTokens
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode:
+		tokens, err = o.exchangeCode(ctx, client, req)
+	case GrantTypeRefreshToken:
+		tokens, err = o.refresh(ctx, client, req.RefreshToken, clientInfo)
+	default:
+		err = &Error{Code: "unsupported_grant_type", Description: "unsupported grant type"}
+	}
+	if err != nil {
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens.ExpiresIn, err = o.tokenTTL(ctx, client.AppID)
+	if err != nil {
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// UserInfo returns the claims about the user the access token is issued to.
+// Errors of Auth.ValidateToken are returned as is.
+func (o *OIDC) UserInfo(ctx context.Context, accessToken string) (models.UserInfo, error) {
+	const op = "OIDC.UserInfo"
+
+	claims, err := o.authenticator.ValidateToken(ctx, accessToken)
+	if err != nil {
+		return models.UserInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := o.userProvider.UserByID(ctx, claims.UID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.UserInfo{}, fmt.Errorf("%s: %w", op, auth.ErrInvalidToken)
+		}
+
+		o.log.Error("failed to get user", slog.String("op", op), sl.Err(err))
+
+		return models.UserInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return models.UserInfo{
+		Subject:     strconv.FormatInt(user.ID, 10),
+		Name:        user.Name,
+		Picture:     user.Image,
+		PhoneNumber: user.Phone,
+	}, nil
+}
+
+// issueCode saves an authorization code for the tokens issued to the user on authorization and returns the code.
+// The tokens are stored sealed with a key derived from the code, so that they can't be read without the code.
+func (o *OIDC) issueCode(ctx context.Context, req models.AuthorizeRequest, tokens models.TokenPair) (string, error) {
+	claims, err := o.authenticator.ValidateToken(ctx, tokens.AccessToken)
+	if err != nil {
+		return "", err
+	}
+
+	code, err := secret.Generate(codeSize)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := seal(code, tokens)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	err = o.codeStorage.SaveAuthCode(ctx, models.AuthCode{
+		CodeHash:      secret.Hash(code),
+		ClientID:      req.ClientID,
+		RedirectURI:   req.RedirectURI,
+		UserID:        claims.UID,
+		Scope:         req.Scope,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		Tokens:        sealed,
+		AuthTime:      now,
+		ExpiresAt:     now.Add(o.settings.CodeTTL),
+	})
+	if err != nil {
+		o.log.Error("failed to save authorization code", sl.Err(err))
+
+		return "", err
+	}
+
+	o.log.Info("authorization code issued", slog.String("client_id", req.ClientID), slog.Int64("uid", claims.UID))
+
+	return code, nil
+}
+
+// exchangeCode returns the tokens of the authorization code with an ID token.
+func (o *OIDC) exchangeCode(ctx context.Context, client models.OAuthClient, req models.TokenRequest) (models.OAuthTokens, error) {
+	invalidGrant := &Error{Code: "invalid_grant", Description: "invalid authorization code"}
+
+	code, err := o.codeStorage.ConsumeAuthCode(ctx, secret.Hash(req.Code))
+	if err != nil {
+		if errors.Is(err, storage.ErrAuthCodeNotFound) {
+			return models.OAuthTokens{}, invalidGrant
+		}
+
+		o.log.Error("failed to get authorization code", sl.Err(err))
+
+		return models.OAuthTokens{}, err
+	}
+
+	if code.ClientID != client.ID || code.RedirectURI != req.RedirectURI || time.Now().After(code.ExpiresAt) {
+		return models.OAuthTokens{}, invalidGrant
+	}
+
+	if !verifyCodeChallenge(code.CodeChallenge, req.CodeVerifier) {
+		return models.OAuthTokens{}, &Error{Code: "invalid_grant", Description: "invalid code verifier"}
+	}
+
+	tokens, err := unseal(req.Code, code.Tokens)
+	if err != nil {
+		o.log.Error("failed to unseal tokens", sl.Err(err))
+
+		return models.OAuthTokens{}, err
+	}
+
+	idToken, err := o.idToken(ctx, client, code)
+	if err != nil {
+		o.log.Error("failed to generate id token", sl.Err(err))
+
+		return models.OAuthTokens{}, err
+	}
+
+	return models.OAuthTokens{TokenPair: tokens, IDToken: idToken, Scope: code.Scope}, nil
+}
+
+// refresh exchanges the refresh token issued to the client for a new pair of tokens.
+func (o *OIDC) refresh(
+	ctx context.Context,
+	client models.OAuthClient,
+	refreshToken string,
+	clientInfo models.ClientInfo,
+) (models.OAuthTokens, error) {
+	invalidGrant := &Error{Code: "invalid_grant", Description: "invalid refresh token"}
+
+	tokens, err := o.authenticator.Refresh(ctx, refreshToken, clientInfo)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			return models.OAuthTokens{}, invalidGrant
+		}
+
+		return models.OAuthTokens{}, err
+	}
+
+	claims, err := o.authenticator.ValidateToken(ctx, tokens.AccessToken)
+	if err != nil {
+		return models.OAuthTokens{}, err
+	}
+	if claims.AppID != client.AppID {
+		return models.OAuthTokens{}, invalidGrant
+	}
+
+	return models.OAuthTokens{TokenPair: tokens}, nil
+}
+
+// idToken returns the ID token of the user the authorization code is issued to.
+func (o *OIDC) idToken(ctx context.Context, client models.OAuthClient, code models.AuthCode) (string, error) {
+	var phone string
+	if slices.Contains(strings.Fields(code.Scope), models.OAuthScopePhone) {
+		user, err := o.userProvider.UserByID(ctx, code.UserID)
+		if err != nil {
+			return "", err
+		}
+
+		phone = user.Phone
+	}
+
+	key, err := o.keyProvider.SigningKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	ttl, err := o.tokenTTL(ctx, client.AppID)
+	if err != nil {
+		return "", err
+	}
+
+	issuer := strings.TrimSuffix(o.settings.Issuer, "/")
+
+	return jwt.NewIDToken(code.UserID, phone, client.ID, code.Nonce, code.AuthTime, key, issuer, ttl)
+}
+
+// authenticateClient returns the client if its secret is valid. Public clients have no secret.
+func (o *OIDC) authenticateClient(ctx context.Context, clientID string, clientSecret string) (models.OAuthClient, error) {
+	invalidClient := &Error{Code: "invalid_client", Description: "client authentication failed"}
+
+	client, err := o.clientStorage.OAuthClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrOAuthClientNotFound) {
+			return models.OAuthClient{}, invalidClient
+		}
+
+		return models.OAuthClient{}, err
+	}
+
+	if client.SecretHash != "" &&
+		subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(secret.Hash(clientSecret))) != 1 {
+		return models.OAuthClient{}, invalidClient
+	}
+
+	return client, nil
+}
+
+// tokenTTL returns the lifetime of access tokens of the app.
+func (o *OIDC) tokenTTL(ctx context.Context, appID int64) (time.Duration, error) {
+	app, err := o.appProvider.App(ctx, appID)
+	if err != nil {
+		return 0, err
+	}
+
+	if app.TokenTTL > 0 {
+		return app.TokenTTL, nil
+	}
+
+	return o.settings.TokenTTL, nil
+}
+
+// verifyCodeChallenge checks the PKCE code verifier against the S256 code challenge.
+func verifyCodeChallenge(challenge string, verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return subtle.ConstantTimeCompare([]byte(challenge), []byte(base64.RawURLEncoding.EncodeToString(sum[:]))) == 1
+}
+
+// seal encrypts the tokens with AES-GCM under a key derived from the authorization code.
+func seal(code string, tokens models.TokenPair) ([]byte, error) {
+	gcm, err := codeCipher(code)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// unseal decrypts the tokens sealed with the authorization code.
+func unseal(code string, sealed []byte) (models.TokenPair, error) {
+	gcm, err := codeCipher(code)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return models.TokenPair{}, errors.New("sealed tokens are too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	var tokens models.TokenPair
+	if err := json.Unmarshal(plaintext, &tokens); err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return tokens, nil
+}
+
+func codeCipher(code string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte("oauth-code:" + code))
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
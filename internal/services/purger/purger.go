@@ -0,0 +1,121 @@
+package purger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/storage"
+	"time"
+)
+
+type Storage interface {
+	UsersDeletedBefore(ctx context.Context, t time.Time) ([]int64, error)
+	PurgeUser(ctx context.Context, userID int64, deletedBefore time.Time) error
+}
+
+type Auditor interface {
+	Record(ctx context.Context, event models.AuditEvent)
+}
+
+// UserCache caches users for introspection. A purged user is dropped from it.
+type UserCache interface {
+	ForgetUser(userID int64)
+}
+
+// Purger deletes accounts whose deletion grace period is over.
+type Purger struct {
+	log         *slog.Logger
+	storage     Storage
+	userCache   UserCache
+	auditor     Auditor
+	gracePeriod time.Duration
+	interval    time.Duration
+}
+
+// New returns Purger that deletes accounts gracePeriod after they were marked as deleted,
+// checking for them every interval.
+func New(
+	log *slog.Logger,
+	storage Storage,
+	userCache UserCache,
+	auditor Auditor,
+	gracePeriod time.Duration,
+	interval time.Duration,
+) *Purger {
+	return &Purger{
+		log:         log,
+		storage:     storage,
+		userCache:   userCache,
+		auditor:     auditor,
+		gracePeriod: gracePeriod,
+		interval:    interval,
+	}
+}
+
+// Run purges accounts every interval until the context is done.
+func (p *Purger) Run(ctx context.Context) {
+	const op = "Purger.Run"
+
+	log := p.log.With(slog.String("op", op))
+
+	log.Info("account purger started", slog.Duration("grace_period", p.gracePeriod))
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.Purge(ctx); err != nil {
+			log.Error("failed to purge accounts", sl.Err(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info("account purger stopped")
+
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes every account whose grace period is over with all of its data
+// and records the deletion in the audit log. Returns the number of deleted accounts.
+// An account restored meanwhile is skipped.
+func (p *Purger) Purge(ctx context.Context) (int, error) {
+	const op = "Purger.Purge"
+
+	log := p.log.With(slog.String("op", op))
+
+	deletedBefore := time.Now().Add(-p.gracePeriod)
+
+	userIDs, err := p.storage.UsersDeletedBefore(ctx, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	purged := 0
+	for _, userID := range userIDs {
+		if err := p.storage.PurgeUser(ctx, userID, deletedBefore); err != nil {
+			if errors.Is(err, storage.ErrUserNotDeleted) {
+				continue
+			}
+
+			return purged, fmt.Errorf("%s: %w", op, err)
+		}
+		purged++
+		p.userCache.ForgetUser(userID)
+
+		p.auditor.Record(ctx, models.AuditEvent{
+			Event:   models.AuditEventUserPurge,
+			UserID:  userID,
+			Outcome: models.AuditOutcomeSuccess,
+		})
+
+		log.Info("account purged", slog.Int64("user_id", userID))
+	}
+
+	return purged, nil
+}
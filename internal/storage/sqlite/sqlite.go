@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"github.com/mattn/go-sqlite3"
 	"math/rand"
+	"slices"
 	"sso/internal/domain/models"
 	"sso/internal/storage"
+	"strings"
 	"time"
 )
 
@@ -34,26 +36,30 @@ func (s *Storage) Stop() error {
 func (s *Storage) SaveUser(ctx context.Context, phone string, passHash []byte) (int64, error) {
 	const op = "storage.sqlite.SaveUser"
 
-	stmt, err := s.db.Prepare("INSERT INTO users(phone, pass_hash, name, image) VALUES(?, ?, ?, ?)")
+	id, err := s.saveUser(ctx, phone, passHash, nil)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return id, fmt.Errorf("%s: %w", op, err)
 	}
 
-	authorIDs, err := s.getAllIDsFromAuthors(ctx)
-	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
-	}
+	return id, nil
+}
 
-	articleIDs, err := s.getAllIDsFromArticles(ctx)
-	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
-	}
+// SaveGuest saves a guest user bound to the device. The guest has neither a phone nor a password.
+// If there is a guest on the device already, returns storage.ErrUserExists.
+func (s *Storage) SaveGuest(ctx context.Context, deviceID string) (int64, error) {
+	const op = "storage.sqlite.SaveGuest"
 
-	poetIDs, err := s.getAllIDsFromPoets(ctx)
+	id, err := s.saveUser(ctx, nil, []byte{}, deviceID)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return id, fmt.Errorf("%s: %w", op, err)
 	}
 
+	return id, nil
+}
+
+// saveUser saves a user with the default role. Favorites are stored sparsely, so a new user has none of them.
+// Registered users have a phone, guests have a device ID instead.
+func (s *Storage) saveUser(ctx context.Context, phone any, passHash []byte, deviceID any) (int64, error) {
 	authorsName := map[int]string{
 		0: "Фёдор Достоевский",
 		1: "Антон Чехов",
@@ -69,132 +75,152 @@ func (s *Storage) SaveUser(ctx context.Context, phone string, passHash []byte) (
 	rand.New(rand.NewSource(time.Now().UnixNano()))
 	randomKey := rand.Intn(len(authorsName))
 
-	res, err := stmt.ExecContext(ctx, phone, passHash, authorsName[randomKey], authorsImage[randomKey])
+	// The user and the role are saved together, so that there are no users without a role.
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO users(phone, pass_hash, name, image, device_id) VALUES(?, ?, ?, ?, ?)",
+		phone, passHash, authorsName[randomKey], authorsImage[randomKey], deviceID,
+	)
 	if err != nil {
 		var sqliteErr sqlite3.Error
 		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
-			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+			return 0, storage.ErrUserExists
 		}
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return 0, err
 	}
 
 	id, err := res.LastInsertId()
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
-	}
-
-	for _, authorID := range authorIDs {
-		_, err = s.db.ExecContext(ctx, "INSERT INTO authors(userId, authorId, isFave) VALUES(?, ?, 0)", id, authorID)
-		if err != nil {
-			return id, fmt.Errorf("%s: failed to insert author: %w", op, err)
-		}
+		return 0, err
 	}
 
-	for _, articleID := range articleIDs {
-		_, err = s.db.ExecContext(ctx, "INSERT INTO articles(userId, articleId, isFave) VALUES(?, ?, 0)", id, articleID)
-		if err != nil {
-			return id, fmt.Errorf("%s: failed to insert article: %w", op, err)
-		}
+	_, err = tx.ExecContext(ctx, "INSERT INTO user_roles(user_id, role) VALUES(?, ?)", id, models.RoleUser)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert role: %w", err)
 	}
 
-	for _, poetID := range poetIDs {
-		_, err = s.db.ExecContext(ctx, "INSERT INTO poets(userId, poetId, isFave) VALUES(?, ?, 0)", id, poetID)
-		if err != nil {
-			return id, fmt.Errorf("%s: failed to insert poet: %w", op, err)
-		}
+	if err := tx.Commit(); err != nil {
+		return 0, err
 	}
 
 	return id, nil
 }
 
-func (s *Storage) getAllIDsFromAuthors(ctx context.Context) ([]int, error) {
-	query := fmt.Sprintf("SELECT id FROM author")
-	rows, err := s.db.QueryContext(ctx, query)
+// User returns user by phone.
+func (s *Storage) User(ctx context.Context, phone string) (models.User, error) {
+	const op = "storage.sqlite.User"
+
+	stmt, err := s.db.Prepare("SELECT id, phone, pass_hash, name, image, device_id, deleted_at FROM users WHERE phone = ?")
 	if err != nil {
-		return nil, err
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
-	defer rows.Close()
 
-	var ids []int
-	for rows.Next() {
-		var id int
-		if err := rows.Scan(&id); err != nil {
-			return nil, err
+	user, err := scanUser(stmt.QueryRowContext(ctx, phone))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
 		}
-		ids = append(ids, id)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return ids, nil
+	return user, nil
 }
 
-func (s *Storage) getAllIDsFromArticles(ctx context.Context) ([]int, error) {
-	query := fmt.Sprintf("SELECT id FROM article")
-	rows, err := s.db.QueryContext(ctx, query)
+// UserByID returns user by id.
+func (s *Storage) UserByID(ctx context.Context, userID int64) (models.User, error) {
+	const op = "storage.sqlite.UserByID"
+
+	stmt, err := s.db.Prepare("SELECT id, phone, pass_hash, name, image, device_id, deleted_at FROM users WHERE id = ?")
 	if err != nil {
-		return nil, err
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
-	defer rows.Close()
 
-	var ids []int
-	for rows.Next() {
-		var id int
-		if err := rows.Scan(&id); err != nil {
-			return nil, err
+	user, err := scanUser(stmt.QueryRowContext(ctx, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
 		}
-		ids = append(ids, id)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return ids, nil
+	return user, nil
 }
 
-func (s *Storage) getAllIDsFromPoets(ctx context.Context) ([]int, error) {
-	query := fmt.Sprintf("SELECT id FROM poet")
-	rows, err := s.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+// Guest returns the guest user bound to the device.
+func (s *Storage) Guest(ctx context.Context, deviceID string) (models.User, error) {
+	const op = "storage.sqlite.Guest"
 
-	var ids []int
-	for rows.Next() {
-		var id int
-		if err := rows.Scan(&id); err != nil {
-			return nil, err
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, phone, pass_hash, name, image, device_id, deleted_at FROM users WHERE device_id = ?
+	`, deviceID)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
 		}
-		ids = append(ids, id)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return ids, nil
+	return user, nil
 }
 
-// User returns user by phone.
-func (s *Storage) User(ctx context.Context, phone string) (models.User, error) {
-	const op = "storage.sqlite.User"
+// UpgradeGuest turns the guest into a registered user with the phone and the password hash.
+// The user keeps its ID, so everything saved by the guest stays with the user.
+// If there is no such guest, returns storage.ErrUserNotFound.
+// If the phone belongs to another user, returns storage.ErrUserExists.
+func (s *Storage) UpgradeGuest(ctx context.Context, userID int64, phone string, passHash []byte) error {
+	const op = "storage.sqlite.UpgradeGuest"
 
-	stmt, err := s.db.Prepare("SELECT id, phone, pass_hash, name, image FROM users WHERE phone = ?")
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE users SET phone = ?, pass_hash = ?, device_id = NULL WHERE id = ? AND device_id IS NOT NULL
+	`, phone, passHash, userID)
 	if err != nil {
-		return models.User{}, fmt.Errorf("%s: %w", op, err)
-	}
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
 
-	row := stmt.QueryRowContext(ctx, phone)
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
-	var user models.User
-	err = row.Scan(&user.ID, &user.Phone, &user.PassHash, &user.Name, &user.Image)
+	affected, err := res.RowsAffected()
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
-		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
 
-		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	return nil
+}
+
+// scanUser scans a row of id, phone, pass_hash, name, image, device_id and deleted_at columns of users.
+func scanUser(row rowScanner) (models.User, error) {
+	var (
+		user      models.User
+		phone     sql.NullString
+		deviceID  sql.NullString
+		deletedAt sql.NullInt64
+	)
+
+	if err := row.Scan(&user.ID, &phone, &user.PassHash, &user.Name, &user.Image, &deviceID, &deletedAt); err != nil {
+		return models.User{}, err
+	}
+
+	user.Phone = phone.String
+	user.Guest = deviceID.Valid
+	if deletedAt.Valid {
+		t := time.Unix(deletedAt.Int64, 0)
+		user.DeletedAt = &t
 	}
 
 	return user, nil
@@ -204,15 +230,18 @@ func (s *Storage) User(ctx context.Context, phone string) (models.User, error) {
 func (s *Storage) GetUser(ctx context.Context, userId int64) (models.UserData, error) {
 	const op = "storage.sqlite.GetUser"
 
-	stmt, err := s.db.Prepare("SELECT name, image FROM users WHERE id = ?")
+	stmt, err := s.db.Prepare("SELECT name, image, deleted_at FROM users WHERE id = ?")
 	if err != nil {
 		return models.UserData{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	row := stmt.QueryRowContext(ctx, userId)
 
-	var user models.UserData
-	err = row.Scan(&user.Name, &user.Image)
+	var (
+		user      models.UserData
+		deletedAt sql.NullInt64
+	)
+	err = row.Scan(&user.Name, &user.Image, &deletedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.UserData{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
@@ -221,30 +250,27 @@ func (s *Storage) GetUser(ctx context.Context, userId int64) (models.UserData, e
 		return models.UserData{}, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if deletedAt.Valid {
+		t := time.Unix(deletedAt.Int64, 0)
+		user.DeletedAt = &t
+	}
+
 	return user, nil
 }
 
-// Authors retrieves authors from the database for a given user ID.
-func (s *Storage) Authors(ctx context.Context, userId int64) ([]models.Author, error) {
+// Authors retrieves a page of authors matching the filter, marking the ones in favorites of the user.
+func (s *Storage) Authors(ctx context.Context, userId int64, filter models.CatalogFilter) ([]models.Author, error) {
 	const op = "storage.sqlite.GetAuthors"
 
-	stmt, err := s.db.Prepare(`
-		SELECT a.id, a.name, a.image, a.clip, ua.isFave
-		FROM author AS a
-		INNER JOIN authors AS ua ON a.id = ua.authorId
-		WHERE ua.userId = ?
-	`)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
-	}
+	query, args := catalogQuery(authorCatalog.favoriteCatalog, "c.id, c.name, c.image, c.clip", userId, filter)
 
-	rows, err := stmt.QueryContext(ctx, userId)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer rows.Close()
 
-	var authors []models.Author
+	authors := []models.Author{}
 	for rows.Next() {
 		var author models.Author
 		err := rows.Scan(&author.ID, &author.Name, &author.Image, &author.Clip, &author.IsFave)
@@ -261,27 +287,21 @@ func (s *Storage) Authors(ctx context.Context, userId int64) ([]models.Author, e
 	return authors, nil
 }
 
-// Articles retrieves articles from the database for a given user ID.
-func (s *Storage) Articles(ctx context.Context, userId int64) ([]models.Article, error) {
+// Articles retrieves a page of articles matching the filter, marking the ones in favorites of the user.
+func (s *Storage) Articles(ctx context.Context, userId int64, filter models.CatalogFilter) ([]models.Article, error) {
 	const op = "storage.sqlite.GetArticles"
 
-	stmt, err := s.db.Prepare(`
-		SELECT a.id, a.name, a.image, a.clip, a.description, ua.isFave
-		FROM article AS a
-		INNER JOIN articles AS ua ON a.id = ua.articleId
-		WHERE ua.userId = ?
-	`)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
-	}
+	query, args := catalogQuery(
+		articleCatalog.favoriteCatalog, "c.id, c.name, c.image, c.clip, c.description", userId, filter,
+	)
 
-	rows, err := stmt.QueryContext(ctx, userId)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer rows.Close()
 
-	var articles []models.Article
+	articles := []models.Article{}
 	for rows.Next() {
 		var article models.Article
 		err := rows.Scan(&article.ID, &article.Name, &article.Image, &article.Clip, &article.Description, &article.IsFave)
@@ -298,27 +318,19 @@ func (s *Storage) Articles(ctx context.Context, userId int64) ([]models.Article,
 	return articles, nil
 }
 
-// Poets retrieves poets from the database for a given user ID.
-func (s *Storage) Poets(ctx context.Context, userId int64) ([]models.Poet, error) {
+// Poets retrieves a page of poets matching the filter, marking the ones in favorites of the user.
+func (s *Storage) Poets(ctx context.Context, userId int64, filter models.CatalogFilter) ([]models.Poet, error) {
 	const op = "storage.sqlite.GetPoets"
 
-	stmt, err := s.db.Prepare(`
-		SELECT p.id, p.name, p.image, p.clip, ua.isFave
-		FROM poet AS p
-		INNER JOIN poets AS ua ON p.id = ua.poetId
-		WHERE ua.userId = ?
-	`)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
-	}
+	query, args := catalogQuery(poetCatalog.favoriteCatalog, "c.id, c.name, c.image, c.clip", userId, filter)
 
-	rows, err := stmt.QueryContext(ctx, userId)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer rows.Close()
 
-	var poets []models.Poet
+	poets := []models.Poet{}
 	for rows.Next() {
 		var poet models.Poet
 		err := rows.Scan(&poet.ID, &poet.Name, &poet.Image, &poet.Clip, &poet.IsFave)
@@ -335,103 +347,176 @@ func (s *Storage) Poets(ctx context.Context, userId int64) ([]models.Poet, error
 	return poets, nil
 }
 
-// DeleteUser deletes a user by their ID.
-func (s *Storage) DeleteUser(ctx context.Context, userID int64) error {
-	const op = "storage.sqlite.DeleteUser"
+// SoftDeleteUser marks the user as deleted at the given time. The user and their data stay in the storage
+// until PurgeUser. If the user is marked already, the original time is kept.
+// If there is no such user, returns storage.ErrUserNotFound.
+func (s *Storage) SoftDeleteUser(ctx context.Context, userID int64, deletedAt time.Time) error {
+	const op = "storage.sqlite.SoftDeleteUser"
 
-	stmt, err := s.db.Prepare("DELETE FROM users WHERE id = ?")
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE users SET deleted_at = COALESCE(deleted_at, ?) WHERE id = ?",
+		deletedAt.Unix(), userID,
+	)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	stmtAuthors, err := s.db.Prepare("DELETE FROM authors WHERE userId = ?")
+	affected, err := res.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-
-	stmtArticles, err := s.db.Prepare("DELETE FROM articles WHERE userId = ?")
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
 	}
 
-	stmtPoets, err := s.db.Prepare("DELETE FROM poets WHERE userId = ?")
+	return nil
+}
+
+// RestoreUser cancels the deletion of the user. If the user is not marked as deleted, returns storage.ErrUserNotDeleted.
+func (s *Storage) RestoreUser(ctx context.Context, userID int64) error {
+	const op = "storage.sqlite.RestoreUser"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE users SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL",
+		userID,
+	)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = stmt.ExecContext(ctx, userID)
-	_, err = stmtAuthors.ExecContext(ctx, userID)
-	_, err = stmtArticles.ExecContext(ctx, userID)
-	_, err = stmtPoets.ExecContext(ctx, userID)
+	affected, err := res.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotDeleted)
+	}
 
 	return nil
 }
 
-// UpdateAuthorIsFave updates the isFave field for an author in the authors table by their userID and authorID.
-func (s *Storage) UpdateAuthorIsFave(ctx context.Context, userId int64, authorId int64) error {
-	const op = "storage.sqlite.UpdateAuthorIsFave"
+// UsersDeletedBefore returns IDs of users marked as deleted before t.
+func (s *Storage) UsersDeletedBefore(ctx context.Context, t time.Time) ([]int64, error) {
+	const op = "storage.sqlite.UsersDeletedBefore"
 
-	var currentIsFave int
-	err := s.db.QueryRowContext(ctx, "SELECT isFave FROM authors WHERE userId = ? AND authorId = ?", userId, authorId).Scan(&currentIsFave)
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id FROM users WHERE deleted_at IS NOT NULL AND deleted_at < ? ORDER BY deleted_at",
+		t.Unix(),
+	)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("%s: author with userId %d and authorId %d not found", op, userId, authorId)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
 		}
-		return fmt.Errorf("%s: %w", op, err)
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	newIsFave := 1 - currentIsFave
+	return ids, nil
+}
+
+// PurgeUser deletes the user marked as deleted before deletedBefore together with all of their data
+// in one transaction. Revocations of the user's tokens and audit events are kept.
+// If the user is not marked as deleted before deletedBefore, e.g. has been restored meanwhile,
+// nothing is deleted and storage.ErrUserNotDeleted is returned.
+func (s *Storage) PurgeUser(ctx context.Context, userID int64, deletedBefore time.Time) error {
+	const op = "storage.sqlite.PurgeUser"
 
-	_, err = s.db.ExecContext(ctx, "UPDATE authors SET isFave = ? WHERE userId = ? AND authorId = ?", newIsFave, userId, authorId)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	return nil
-}
-
-// UpdateArticleIsFave updates the isFave field for an article
-func (s *Storage) UpdateArticleIsFave(ctx context.Context, userId int64, articleId int64) error {
-	const op = "storage.sqlite.UpdateAuthorIsFave"
-
-	var currentIsFave int
-	err := s.db.QueryRowContext(ctx, "SELECT isFave FROM articles WHERE userId = ? AND articleId = ?", userId, articleId).Scan(&currentIsFave)
+	var phone sql.NullString
+	err = tx.QueryRowContext(ctx,
+		"SELECT phone FROM users WHERE id = ? AND deleted_at IS NOT NULL AND deleted_at < ?",
+		userID, deletedBefore.Unix(),
+	).Scan(&phone)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("%s: article with userId %d and articleId %d not found", op, userId, articleId)
+			return fmt.Errorf("%s: %w", op, storage.ErrUserNotDeleted)
 		}
+
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	newIsFave := 1 - currentIsFave
+	for _, query := range []string{
+		"DELETE FROM authors WHERE userId = ?",
+		"DELETE FROM articles WHERE userId = ?",
+		"DELETE FROM poets WHERE userId = ?",
+		"DELETE FROM user_roles WHERE user_id = ?",
+		"DELETE FROM user_totp WHERE user_id = ?",
+		"DELETE FROM recovery_codes WHERE user_id = ?",
+		"DELETE FROM two_factor_challenges WHERE user_id = ?",
+		"DELETE FROM sessions WHERE user_id = ?",
+		"DELETE FROM refresh_tokens WHERE user_id = ?",
+		"DELETE FROM oauth_codes WHERE user_id = ?",
+		"DELETE FROM users WHERE id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
 
-	_, err = s.db.ExecContext(ctx, "UPDATE articles SET isFave = ? WHERE userId = ? AND articleId = ?", newIsFave, userId, articleId)
-	if err != nil {
+	if phone.Valid {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM otp_codes WHERE phone = ?", phone.String); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		_, err := tx.ExecContext(ctx, "DELETE FROM login_attempts WHERE kind = 'phone' AND subject = ?", phone.String)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-// UpdatePoetIsFave updates the isFave field for a poet
-func (s *Storage) UpdatePoetIsFave(ctx context.Context, userId int64, poetId int64) error {
-	const op = "storage.sqlite.UpdateAuthorIsFave"
-
-	var currentIsFave int
-	err := s.db.QueryRowContext(ctx, "SELECT isFave FROM poets WHERE userId = ? AND poetId = ?", userId, poetId).Scan(&currentIsFave)
+// AddFavorite adds the content item of the type to favorites of the user at the given time.
+// If it is there already, the original time is kept.
+// If there is no such item, returns storage.ErrContentNotFound.
+func (s *Storage) AddFavorite(ctx context.Context, userID int64, contentType string, id int64, favoritedAt time.Time) error {
+	const op = "storage.sqlite.AddFavorite"
+
+	err := s.writeFavorite(ctx, contentType, id, func(tx *sql.Tx, f favoriteCatalog) error {
+		_, err := tx.ExecContext(ctx,
+			fmt.Sprintf("INSERT OR IGNORE INTO %s(userId, %s, favorited_at) VALUES(?, ?, ?)", f.favorites, f.favoriteColumn),
+			userID, id, favoritedAt.Unix(),
+		)
+		return err
+	})
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("%s: poet with userId %d and poetId %d not found", op, userId, poetId)
-		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	newIsFave := 1 - currentIsFave
+	return nil
+}
 
-	_, err = s.db.ExecContext(ctx, "UPDATE poets SET isFave = ? WHERE userId = ? AND poetId = ?", newIsFave, userId, poetId)
+// RemoveFavorite removes the content item of the type from favorites of the user. If it isn't there, does nothing.
+// If there is no such item, returns storage.ErrContentNotFound.
+func (s *Storage) RemoveFavorite(ctx context.Context, userID int64, contentType string, id int64) error {
+	const op = "storage.sqlite.RemoveFavorite"
+
+	err := s.writeFavorite(ctx, contentType, id, func(tx *sql.Tx, f favoriteCatalog) error {
+		_, err := tx.ExecContext(ctx,
+			fmt.Sprintf("DELETE FROM %s WHERE userId = ? AND %s = ?", f.favorites, f.favoriteColumn),
+			userID, id,
+		)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -439,116 +524,233 @@ func (s *Storage) UpdatePoetIsFave(ctx context.Context, userId int64, poetId int
 	return nil
 }
 
-// GetClip retrieves a clip from the database by ID.
-func (s *Storage) GetClip(ctx context.Context, clipID int64) (models.Clip, error) {
-	const op = "storage.sqlite.GetClip"
+// writeFavorite checks that the content item of the type exists and runs write in the same transaction.
+// If there is no such item, returns storage.ErrContentNotFound.
+func (s *Storage) writeFavorite(
+	ctx context.Context,
+	contentType string,
+	id int64,
+	write func(tx *sql.Tx, f favoriteCatalog) error,
+) error {
+	f, ok := favoriteCatalogs[contentType]
+	if !ok {
+		return storage.ErrContentNotFound
+	}
 
-	stmtClipText, err := s.db.Prepare("SELECT text, title FROM clipText WHERE clipTextId = ?")
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+		return err
 	}
-	defer stmtClipText.Close()
+	defer func() { _ = tx.Rollback() }()
 
-	rows, err := stmtClipText.QueryContext(ctx, clipID)
+	var exists bool
+	err = tx.QueryRowContext(ctx, fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE id = ?)", f.table), id).
+		Scan(&exists)
 	if err != nil {
-		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+		return err
+	}
+	if !exists {
+		return storage.ErrContentNotFound
 	}
-	defer rows.Close()
 
-	var clipTexts []models.ClipText
+	if err := write(tx, f); err != nil {
+		return err
+	}
 
-	for rows.Next() {
-		var clipText models.ClipText
-		if err := rows.Scan(&clipText.Text, &clipText.Title); err != nil {
-			return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	return tx.Commit()
+}
+
+// Favorites returns favorites of the user matching the filter with their details,
+// the recently added first unless the filter is ascending.
+func (s *Storage) Favorites(ctx context.Context, userID int64, filter models.FavoriteFilter) ([]models.Favorite, error) {
+	const op = "storage.sqlite.Favorites"
+
+	var (
+		selects []string
+		args    []any
+	)
+
+	// Favorites added in the same second are sorted by the rank of their type, the index in models.FavoriteTypes,
+	// so that the order doesn't depend on how type names compare.
+	for rank, contentType := range models.FavoriteTypes {
+		if filter.Type != "" && filter.Type != contentType {
+			continue
 		}
-		clipTexts = append(clipTexts, clipText)
+
+		f := favoriteCatalogs[contentType]
+		selects = append(selects, fmt.Sprintf(`
+			SELECT ? AS type, CAST(? AS INTEGER) AS type_rank, c.id, c.name, c.image, c.clip, f.favorited_at
+			FROM %s AS f
+			INNER JOIN %s AS c ON c.id = f.%s
+			WHERE f.userId = ?`,
+			f.favorites, f.table, f.favoriteColumn,
+		))
+		args = append(args, contentType, rank, userID)
 	}
-	if err := rows.Err(); err != nil {
-		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+
+	order, compare := "DESC", "<"
+	if filter.Ascending {
+		order, compare = "ASC", ">"
 	}
 
-	stmtClip, err := s.db.Prepare("SELECT id, image FROM clip WHERE id = ?")
-	if err != nil {
-		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	query := "SELECT type, id, name, image, clip, favorited_at FROM (" + strings.Join(selects, " UNION ALL ") + ")"
+	if filter.After != nil {
+		query += fmt.Sprintf(" WHERE (favorited_at, type_rank, id) %s (?, ?, ?)", compare)
+		args = append(args, filter.After.FavoritedAt, slices.Index(models.FavoriteTypes, filter.After.Type), filter.After.ID)
 	}
-	defer stmtClip.Close()
+	query += fmt.Sprintf(" ORDER BY favorited_at %[1]s, type_rank %[1]s, id %[1]s LIMIT ?", order)
+	args = append(args, filter.Limit)
 
-	var clip models.Clip
-	err = stmtClip.QueryRowContext(ctx, clipID).Scan(&clip.ID, &clip.Image)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	favorites := []models.Favorite{}
+	for rows.Next() {
+		var (
+			favorite    models.Favorite
+			favoritedAt int64
+		)
+
+		err := rows.Scan(&favorite.Type, &favorite.ID, &favorite.Name, &favorite.Image, &favorite.Clip, &favoritedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		t := time.Unix(favoritedAt, 0)
+		favorite.FavoritedAt = &t
+		favorite.IsFave = true
+
+		favorites = append(favorites, favorite)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	quiz, err := s.GetQuiz(ctx, clipID)
+	return favorites, nil
+}
+
+// GetClip retrieves a clip from the database by ID, with its quiz if it has one.
+// If there is no such clip, returns storage.ErrClipNotFound.
+func (s *Storage) GetClip(ctx context.Context, clipID int64) (models.Clip, error) {
+	const op = "storage.sqlite.GetClip"
+
+	clip, err := getClip(ctx, s.db, clipID)
 	if err != nil {
 		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	clip.Text = clipTexts
-	clip.Quiz = quiz
-
 	return clip, nil
 }
 
-// GetQuiz retrieves a quiz from the database by ID.
+// GetQuiz retrieves a quiz from the database by ID. If there is no such quiz, returns storage.ErrQuizNotFound.
 func (s *Storage) GetQuiz(ctx context.Context, quizID int64) (models.Quiz, error) {
 	const op = "storage.sqlite.GetQuiz"
 
-	stmtAnswers, err := s.db.Prepare("SELECT id, text, isRight FROM answers WHERE id = ?")
+	quiz, err := getQuiz(ctx, s.db, quizID)
 	if err != nil {
 		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
 	}
-	defer stmtAnswers.Close()
 
-	rows, err := stmtAnswers.QueryContext(ctx, quizID)
+	return quiz, nil
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx, so that content can be read inside the transaction writing it.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// getClip reads the clip with its text and quiz. A clip without a quiz has a zero Quiz.
+func getClip(ctx context.Context, q querier, clipID int64) (models.Clip, error) {
+	var clip models.Clip
+	err := q.QueryRowContext(ctx, "SELECT id, image FROM clip WHERE id = ?", clipID).Scan(&clip.ID, &clip.Image)
 	if err != nil {
-		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Clip{}, storage.ErrClipNotFound
+		}
+
+		return models.Clip{}, err
 	}
-	defer rows.Close()
 
-	var answers []models.Answer
+	rows, err := q.QueryContext(ctx, "SELECT text, title FROM clipText WHERE clipTextId = ? ORDER BY rowid", clipID)
+	if err != nil {
+		return models.Clip{}, err
+	}
+	defer rows.Close()
 
 	for rows.Next() {
-		var answer models.Answer
-		if err := rows.Scan(&answer.ID, &answer.Text, &answer.IsRight); err != nil {
-			return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+		var clipText models.ClipText
+		if err := rows.Scan(&clipText.Text, &clipText.Title); err != nil {
+			return models.Clip{}, err
 		}
-		answers = append(answers, answer)
+		clip.Text = append(clip.Text, clipText)
 	}
 	if err := rows.Err(); err != nil {
-		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+		return models.Clip{}, err
 	}
 
-	stmtQuiz, err := s.db.Prepare("SELECT id, question, description, image FROM quiz WHERE id = ?")
-	if err != nil {
-		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	quiz, err := getQuiz(ctx, q, clipID)
+	if err != nil && !errors.Is(err, storage.ErrQuizNotFound) {
+		return models.Clip{}, err
 	}
-	defer stmtQuiz.Close()
+	clip.Quiz = quiz
+
+	return clip, nil
+}
 
+// getQuiz reads the quiz with its answers.
+func getQuiz(ctx context.Context, q querier, quizID int64) (models.Quiz, error) {
 	var quiz models.Quiz
-	err = stmtQuiz.QueryRowContext(ctx, quizID).Scan(&quiz.ID, &quiz.Question, &quiz.Description, &quiz.Image)
+	err := q.QueryRowContext(ctx,
+		"SELECT id, question, description, image FROM quiz WHERE id = ?", quizID,
+	).Scan(&quiz.ID, &quiz.Question, &quiz.Description, &quiz.Image)
 	if err != nil {
-		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Quiz{}, storage.ErrQuizNotFound
+		}
+
+		return models.Quiz{}, err
+	}
+
+	rows, err := q.QueryContext(ctx, "SELECT id, text, isRight FROM answers WHERE id = ? ORDER BY rowid", quizID)
+	if err != nil {
+		return models.Quiz{}, err
 	}
+	defer rows.Close()
 
-	quiz.Answers = answers
+	for rows.Next() {
+		var answer models.Answer
+		if err := rows.Scan(&answer.ID, &answer.Text, &answer.IsRight); err != nil {
+			return models.Quiz{}, err
+		}
+		quiz.Answers = append(quiz.Answers, answer)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Quiz{}, err
+	}
 
 	return quiz, nil
 }
 
-func (s *Storage) App(ctx context.Context) (models.App, error) {
+// App returns app by id.
+func (s *Storage) App(ctx context.Context, appID int64) (models.App, error) {
 	const op = "storage.sqlite.App"
 
-	stmt, err := s.db.Prepare("SELECT name, secret FROM apps")
+	stmt, err := s.db.Prepare("SELECT id, name, secret, token_ttl FROM apps WHERE id = ?")
 	if err != nil {
 		return models.App{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	row := stmt.QueryRowContext(ctx)
+	row := stmt.QueryRowContext(ctx, appID)
 
-	var app models.App
-	err = row.Scan(&app.Name, &app.Secret)
+	var (
+		app      models.App
+		tokenTTL int64
+	)
+	err = row.Scan(&app.ID, &app.Name, &app.Secret, &tokenTTL)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
@@ -557,5 +759,2218 @@ func (s *Storage) App(ctx context.Context) (models.App, error) {
 		return models.App{}, fmt.Errorf("%s: %w", op, err)
 	}
 
+	app.TokenTTL = time.Duration(tokenTTL) * time.Second
+
 	return app, nil
 }
+
+// SaveRefreshToken saves a new refresh token.
+func (s *Storage) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	const op = "storage.sqlite.SaveRefreshToken"
+
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO refresh_tokens(user_id, app_id, family_id, token_hash, expires_at) VALUES(?, ?, ?, ?, ?)",
+		token.UserID, token.AppID, token.FamilyID, token.TokenHash, token.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RefreshToken returns refresh token by its hash.
+func (s *Storage) RefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	const op = "storage.sqlite.RefreshToken"
+
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, user_id, app_id, family_id, token_hash, expires_at, used_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = ?
+	`, tokenHash)
+
+	var (
+		token     models.RefreshToken
+		expiresAt int64
+		usedAt    sql.NullInt64
+		revokedAt sql.NullInt64
+	)
+	err := row.Scan(&token.ID, &token.UserID, &token.AppID, &token.FamilyID, &token.TokenHash, &expiresAt, &usedAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RefreshToken{}, fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenNotFound)
+		}
+
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	token.ExpiresAt = time.Unix(expiresAt, 0)
+	token.Used = usedAt.Valid
+	token.Revoked = revokedAt.Valid
+
+	return token, nil
+}
+
+// RotateRefreshToken marks the used refresh token and saves its replacement in one transaction.
+// If the token has already been used, returns storage.ErrRefreshTokenUsed.
+func (s *Storage) RotateRefreshToken(ctx context.Context, usedID int64, next models.RefreshToken) error {
+	const op = "storage.sqlite.RotateRefreshToken"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE refresh_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL",
+		time.Now().Unix(), usedID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenUsed)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO refresh_tokens(user_id, app_id, family_id, token_hash, expires_at) VALUES(?, ?, ?, ?, ?)",
+		next.UserID, next.AppID, next.FamilyID, next.TokenHash, next.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token issued in the given family and the session of the family.
+func (s *Storage) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	const op = "storage.sqlite.RevokeRefreshTokenFamily"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().Unix()
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL",
+		now, familyID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL",
+		now, familyID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeToken revokes the access token with the given ID until it expires.
+func (s *Storage) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	const op = "storage.sqlite.RevokeToken"
+
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO revoked_tokens(jti, expires_at) VALUES(?, ?) ON CONFLICT(jti) DO NOTHING",
+		jti, expiresAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.purgeRevokedTokens(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeUserTokens revokes every access token issued to the user so far and all of the user's refresh tokens and sessions.
+// Access tokens are rejected until expiresAt, after which all of them have expired anyway.
+func (s *Storage) RevokeUserTokens(ctx context.Context, userID int64, expiresAt time.Time) error {
+	const op = "storage.sqlite.RevokeUserTokens"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	revokedAt := time.Now()
+	now := revokedAt.Unix()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO revoked_users(user_id, revoked_at_ms, expires_at) VALUES(?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET revoked_at_ms = excluded.revoked_at_ms, expires_at = excluded.expires_at
+	`, userID, revokedAt.UnixMilli(), expiresAt.Unix())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL",
+		now, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL",
+		now, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.purgeRevokedTokens(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// IsTokenRevoked reports whether the access token with the given ID, issued to the user at issuedAt, is revoked.
+// Revocations of the user are compared with issuedAt in milliseconds, so that a token issued right after
+// a password change stays valid while the ones issued before it in the same second are revoked.
+func (s *Storage) IsTokenRevoked(ctx context.Context, jti string, userID int64, issuedAt time.Time) (bool, error) {
+	const op = "storage.sqlite.IsTokenRevoked"
+
+	now := time.Now().Unix()
+
+	var revoked bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = ? AND expires_at > ?)
+		    OR EXISTS(SELECT 1 FROM revoked_users WHERE user_id = ? AND revoked_at_ms >= ? AND expires_at > ?)
+	`, jti, now, userID, issuedAt.UnixMilli(), now).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return revoked, nil
+}
+
+// purgeRevokedTokens removes revocation entries of tokens that have already expired.
+func (s *Storage) purgeRevokedTokens(ctx context.Context) error {
+	now := time.Now().Unix()
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expires_at <= ?", now); err != nil {
+		return err
+	}
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM revoked_users WHERE expires_at <= ?", now); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LongestAppTokenTTL returns the longest access token lifetime overridden by an app.
+func (s *Storage) LongestAppTokenTTL(ctx context.Context) (time.Duration, error) {
+	const op = "storage.sqlite.LongestAppTokenTTL"
+
+	var ttl int64
+	err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(token_ttl), 0) FROM apps").Scan(&ttl)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return time.Duration(ttl) * time.Second, nil
+}
+
+// ActiveSigningKey returns the newest signing key that has not been retired.
+func (s *Storage) ActiveSigningKey(ctx context.Context) (models.SigningKey, error) {
+	const op = "storage.sqlite.ActiveSigningKey"
+
+	row := s.db.QueryRowContext(ctx, `
+		SELECT kid, algorithm, private_key, public_key, created_at, expires_at
+		FROM signing_keys
+		WHERE retired_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1
+	`)
+
+	key, err := scanSigningKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.SigningKey{}, fmt.Errorf("%s: %w", op, storage.ErrSigningKeyNotFound)
+		}
+
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// SigningKey returns signing key by its ID.
+func (s *Storage) SigningKey(ctx context.Context, kid string) (models.SigningKey, error) {
+	const op = "storage.sqlite.SigningKey"
+
+	row := s.db.QueryRowContext(ctx, `
+		SELECT kid, algorithm, private_key, public_key, created_at, expires_at
+		FROM signing_keys
+		WHERE kid = ?
+	`, kid)
+
+	key, err := scanSigningKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.SigningKey{}, fmt.Errorf("%s: %w", op, storage.ErrSigningKeyNotFound)
+		}
+
+		return models.SigningKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// PublishedSigningKeys returns the keys tokens may still be verified with: the active ones
+// and the retired ones that have not expired yet.
+func (s *Storage) PublishedSigningKeys(ctx context.Context) ([]models.SigningKey, error) {
+	const op = "storage.sqlite.PublishedSigningKeys"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT kid, algorithm, private_key, public_key, created_at, expires_at
+		FROM signing_keys
+		WHERE expires_at IS NULL OR expires_at > ?
+		ORDER BY created_at DESC
+	`, time.Now().Unix())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var keys []models.SigningKey
+	for rows.Next() {
+		key, err := scanSigningKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return keys, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// RotateSigningKey retires the active signing keys, keeping them published until retiredExpireAt,
+// and saves the next key in one transaction.
+func (s *Storage) RotateSigningKey(ctx context.Context, next models.SigningKey, retiredExpireAt time.Time) error {
+	const op = "storage.sqlite.RotateSigningKey"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE signing_keys SET retired_at = ?, expires_at = ? WHERE retired_at IS NULL",
+		time.Now().Unix(), retiredExpireAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO signing_keys(kid, algorithm, private_key, public_key, created_at) VALUES(?, ?, ?, ?, ?)",
+		next.ID, next.Algorithm, next.PrivateKey, next.PublicKey, next.CreatedAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSigningKey(row rowScanner) (models.SigningKey, error) {
+	var (
+		key       models.SigningKey
+		createdAt int64
+		expiresAt sql.NullInt64
+	)
+
+	err := row.Scan(&key.ID, &key.Algorithm, &key.PrivateKey, &key.PublicKey, &createdAt, &expiresAt)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	key.CreatedAt = time.Unix(createdAt, 0)
+	if expiresAt.Valid {
+		key.ExpiresAt = time.Unix(expiresAt.Int64, 0)
+	}
+
+	return key, nil
+}
+
+// SaveOTP saves a one-time code, replacing the previous code of the same phone and purpose.
+func (s *Storage) SaveOTP(ctx context.Context, otp models.OTP) error {
+	const op = "storage.sqlite.SaveOTP"
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO otp_codes(phone, purpose, code_hash, expires_at, sent_at, attempts) VALUES(?, ?, ?, ?, ?, 0)
+		ON CONFLICT(phone, purpose) DO UPDATE SET
+			code_hash = excluded.code_hash,
+			expires_at = excluded.expires_at,
+			sent_at = excluded.sent_at,
+			attempts = 0
+	`, otp.Phone, otp.Purpose, otp.CodeHash, otp.ExpiresAt.Unix(), otp.SentAt.Unix())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// OTP returns the one-time code sent to the phone for the given purpose.
+func (s *Storage) OTP(ctx context.Context, phone string, purpose string) (models.OTP, error) {
+	const op = "storage.sqlite.OTP"
+
+	var (
+		otp       models.OTP
+		expiresAt int64
+		sentAt    int64
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT phone, purpose, code_hash, expires_at, sent_at, attempts
+		FROM otp_codes
+		WHERE phone = ? AND purpose = ?
+	`, phone, purpose).Scan(&otp.Phone, &otp.Purpose, &otp.CodeHash, &expiresAt, &sentAt, &otp.Attempts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.OTP{}, fmt.Errorf("%s: %w", op, storage.ErrOTPNotFound)
+		}
+
+		return models.OTP{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	otp.ExpiresAt = time.Unix(expiresAt, 0)
+	otp.SentAt = time.Unix(sentAt, 0)
+
+	return otp, nil
+}
+
+// UseOTPAttempt counts an attempt to enter the one-time code if fewer than maxAttempts have been made.
+// The check and the count are one statement, so that concurrent attempts can't exceed the limit.
+// If the attempts are exhausted or the code is gone, returns storage.ErrOTPAttemptsExhausted.
+func (s *Storage) UseOTPAttempt(ctx context.Context, phone string, purpose string, maxAttempts int) error {
+	const op = "storage.sqlite.UseOTPAttempt"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE otp_codes SET attempts = attempts + 1 WHERE phone = ? AND purpose = ? AND attempts < ?",
+		phone, purpose, maxAttempts,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrOTPAttemptsExhausted)
+	}
+
+	return nil
+}
+
+// DeleteOTP deletes the one-time code so that it cannot be used again.
+// If the code has already been deleted, e.g. by a concurrent verification, returns storage.ErrOTPNotFound.
+func (s *Storage) DeleteOTP(ctx context.Context, phone string, purpose string) error {
+	const op = "storage.sqlite.DeleteOTP"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM otp_codes WHERE phone = ? AND purpose = ?", phone, purpose)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrOTPNotFound)
+	}
+
+	return nil
+}
+
+// UpdatePassHash replaces the password hash of the user.
+func (s *Storage) UpdatePassHash(ctx context.Context, userID int64, passHash []byte) error {
+	const op = "storage.sqlite.UpdatePassHash"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET pass_hash = ? WHERE id = ?", passHash, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// LoginBlockedUntil returns the time until which logins of the given kind and subject, e.g. a phone or an IP,
+// are blocked. Returns zero time if they are not blocked.
+func (s *Storage) LoginBlockedUntil(ctx context.Context, kind string, subject string) (time.Time, error) {
+	const op = "storage.sqlite.LoginBlockedUntil"
+
+	var blockedUntil int64
+	err := s.db.QueryRowContext(ctx,
+		"SELECT blocked_until FROM login_attempts WHERE kind = ? AND subject = ?",
+		kind, subject,
+	).Scan(&blockedUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if blockedUntil == 0 {
+		return time.Time{}, nil
+	}
+
+	return time.Unix(blockedUntil, 0), nil
+}
+
+// RecordLoginFailure counts a failed login of the given kind and subject and returns the number of failures in a row.
+// Failures that happened before resetBefore are forgotten.
+func (s *Storage) RecordLoginFailure(
+	ctx context.Context,
+	kind string,
+	subject string,
+	at time.Time,
+	resetBefore time.Time,
+) (int, error) {
+	const op = "storage.sqlite.RecordLoginFailure"
+
+	var failures int
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO login_attempts(kind, subject, failures, last_failure_at) VALUES(?, ?, 1, ?)
+		ON CONFLICT(kind, subject) DO UPDATE SET
+			failures = CASE WHEN last_failure_at < ? THEN 1 ELSE failures + 1 END,
+			last_failure_at = excluded.last_failure_at
+		RETURNING failures
+	`, kind, subject, at.Unix(), resetBefore.Unix()).Scan(&failures)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.purgeLoginAttempts(ctx, kind, resetBefore); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return failures, nil
+}
+
+// BlockLogin blocks logins of the given kind and subject until the given time.
+func (s *Storage) BlockLogin(ctx context.Context, kind string, subject string, until time.Time) error {
+	const op = "storage.sqlite.BlockLogin"
+
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE login_attempts SET blocked_until = ? WHERE kind = ? AND subject = ?",
+		until.Unix(), kind, subject,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ResetLoginFailures forgets failed logins of the given kind and subject, e.g. after a successful login.
+func (s *Storage) ResetLoginFailures(ctx context.Context, kind string, subject string) error {
+	const op = "storage.sqlite.ResetLoginFailures"
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM login_attempts WHERE kind = ? AND subject = ?", kind, subject)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// purgeLoginAttempts removes counters of the given kind that are neither recent nor blocked anymore.
+func (s *Storage) purgeLoginAttempts(ctx context.Context, kind string, resetBefore time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		"DELETE FROM login_attempts WHERE kind = ? AND last_failure_at < ? AND blocked_until <= ?",
+		kind, resetBefore.Unix(), time.Now().Unix(),
+	)
+
+	return err
+}
+
+// UserPhones returns phones of all users by user ID. Guests have no phones and are skipped.
+func (s *Storage) UserPhones(ctx context.Context) (map[int64]string, error) {
+	const op = "storage.sqlite.UserPhones"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, phone FROM users WHERE phone IS NOT NULL")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	phones := make(map[int64]string)
+	for rows.Next() {
+		var (
+			id    int64
+			phone string
+		)
+		if err := rows.Scan(&id, &phone); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		phones[id] = phone
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return phones, nil
+}
+
+// UpdateUserPhones sets phones of the users by user ID in a single transaction.
+func (s *Storage) UpdateUserPhones(ctx context.Context, phones map[int64]string) error {
+	const op = "storage.sqlite.UpdateUserPhones"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for id, phone := range phones {
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET phone = ? WHERE id = ?", phone, id); err != nil {
+			var sqliteErr sqlite3.Error
+			if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
+				return fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+			}
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// TwoFactorRequired reports whether the user must pass two-factor authentication to log in.
+func (s *Storage) TwoFactorRequired(ctx context.Context, userID int64) (bool, error) {
+	const op = "storage.sqlite.TwoFactorRequired"
+
+	var required bool
+	err := s.db.QueryRowContext(ctx, "SELECT two_factor_required FROM users WHERE id = ?", userID).Scan(&required)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return required, nil
+}
+
+// SetTwoFactorRequired sets whether the user must pass two-factor authentication to log in.
+func (s *Storage) SetTwoFactorRequired(ctx context.Context, userID int64, required bool) error {
+	const op = "storage.sqlite.SetTwoFactorRequired"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET two_factor_required = ? WHERE id = ?", required, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// TOTP returns the authenticator app secret of the user.
+func (s *Storage) TOTP(ctx context.Context, userID int64) (models.TOTP, error) {
+	const op = "storage.sqlite.TOTP"
+
+	var (
+		totp        models.TOTP
+		confirmedAt sql.NullInt64
+	)
+	err := s.db.QueryRowContext(ctx,
+		"SELECT user_id, secret, confirmed_at, last_step FROM user_totp WHERE user_id = ?",
+		userID,
+	).Scan(&totp.UserID, &totp.Secret, &confirmedAt, &totp.LastStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TOTP{}, fmt.Errorf("%s: %w", op, storage.ErrTOTPNotFound)
+		}
+
+		return models.TOTP{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	totp.Confirmed = confirmedAt.Valid
+
+	return totp, nil
+}
+
+// SaveTOTP saves a new unconfirmed secret of the user, replacing the previous unconfirmed one.
+// If the user already has a confirmed secret, returns storage.ErrTOTPExists.
+func (s *Storage) SaveTOTP(ctx context.Context, userID int64, secret string) error {
+	const op = "storage.sqlite.SaveTOTP"
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_totp(user_id, secret) VALUES(?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET secret = excluded.secret, last_step = 0
+		WHERE user_totp.confirmed_at IS NULL
+	`, userID, secret)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if saved == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPExists)
+	}
+
+	return nil
+}
+
+// ConfirmTOTP confirms the secret of the user with a code of the given time step
+// and replaces the user's recovery codes with the new ones.
+func (s *Storage) ConfirmTOTP(ctx context.Context, userID int64, step int64, recoveryCodeHashes []string) error {
+	const op = "storage.sqlite.ConfirmTOTP"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE user_totp SET confirmed_at = ?, last_step = ? WHERE user_id = ? AND confirmed_at IS NULL",
+		time.Now().Unix(), step, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	confirmed, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if confirmed == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPExists)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		_, err := tx.ExecContext(ctx, "INSERT INTO recovery_codes(user_id, code_hash) VALUES(?, ?)", userID, hash)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UseTOTPStep marks the time step of an accepted code as used.
+// If a code of this or a later step has already been accepted, returns storage.ErrTOTPStepUsed.
+func (s *Storage) UseTOTPStep(ctx context.Context, userID int64, step int64) error {
+	const op = "storage.sqlite.UseTOTPStep"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE user_totp SET last_step = ? WHERE user_id = ? AND last_step < ?",
+		step, userID, step,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	used, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if used == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPStepUsed)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks the unused recovery code of the user as used.
+// If there is no such code, returns storage.ErrRecoveryCodeNotFound.
+func (s *Storage) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	const op = "storage.sqlite.UseRecoveryCode"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL",
+		time.Now().Unix(), userID, codeHash,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	used, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if used == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRecoveryCodeNotFound)
+	}
+
+	return nil
+}
+
+// DeleteTOTP deletes the secret and the recovery codes of the user.
+func (s *Storage) DeleteTOTP(ctx context.Context, userID int64) error {
+	const op = "storage.sqlite.DeleteTOTP"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_totp WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SaveTwoFactorChallenge saves the challenge and removes expired ones.
+func (s *Storage) SaveTwoFactorChallenge(ctx context.Context, challenge models.TwoFactorChallenge) error {
+	const op = "storage.sqlite.SaveTwoFactorChallenge"
+
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO two_factor_challenges(challenge_hash, user_id, app_id, expires_at) VALUES(?, ?, ?, ?)",
+		challenge.Hash, challenge.UserID, challenge.AppID, challenge.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.ExecContext(ctx, "DELETE FROM two_factor_challenges WHERE expires_at <= ?", time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// TwoFactorChallenge returns the challenge by its hash.
+func (s *Storage) TwoFactorChallenge(ctx context.Context, challengeHash string) (models.TwoFactorChallenge, error) {
+	const op = "storage.sqlite.TwoFactorChallenge"
+
+	var (
+		challenge models.TwoFactorChallenge
+		expiresAt int64
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT challenge_hash, user_id, app_id, expires_at, attempts
+		FROM two_factor_challenges
+		WHERE challenge_hash = ?
+	`, challengeHash).Scan(&challenge.Hash, &challenge.UserID, &challenge.AppID, &expiresAt, &challenge.Attempts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TwoFactorChallenge{}, fmt.Errorf("%s: %w", op, storage.ErrChallengeNotFound)
+		}
+
+		return models.TwoFactorChallenge{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	challenge.ExpiresAt = time.Unix(expiresAt, 0)
+
+	return challenge, nil
+}
+
+// IncrementTwoFactorChallengeAttempts counts a wrong code entered for the challenge.
+func (s *Storage) IncrementTwoFactorChallengeAttempts(ctx context.Context, challengeHash string) error {
+	const op = "storage.sqlite.IncrementTwoFactorChallengeAttempts"
+
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE two_factor_challenges SET attempts = attempts + 1 WHERE challenge_hash = ?",
+		challengeHash,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteTwoFactorChallenge deletes the challenge so that it cannot be used again.
+// If the challenge has already been deleted, returns storage.ErrChallengeNotFound.
+func (s *Storage) DeleteTwoFactorChallenge(ctx context.Context, challengeHash string) error {
+	const op = "storage.sqlite.DeleteTwoFactorChallenge"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM two_factor_challenges WHERE challenge_hash = ?", challengeHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrChallengeNotFound)
+	}
+
+	return nil
+}
+
+// SaveSession saves a new session and removes expired ones.
+func (s *Storage) SaveSession(ctx context.Context, session models.Session) error {
+	const op = "storage.sqlite.SaveSession"
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO sessions(id, user_id, app_id, device_name, platform, ip, user_agent, created_at, last_seen_at, expires_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		session.ID, session.UserID, session.AppID, session.DeviceName, session.Platform, session.IP, session.UserAgent,
+		session.CreatedAt.Unix(), session.LastSeenAt.Unix(), session.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= ?", time.Now().Unix()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Session returns the session by its ID, revoked or not.
+func (s *Storage) Session(ctx context.Context, id string) (models.Session, error) {
+	const op = "storage.sqlite.Session"
+
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, user_id, app_id, device_name, platform, ip, user_agent, created_at, last_seen_at, expires_at, revoked_at
+		FROM sessions
+		WHERE id = ?
+	`, id)
+
+	session, err := scanSession(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Session{}, fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+		}
+
+		return models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return session, nil
+}
+
+// Sessions returns active sessions of the user, most recently seen first.
+func (s *Storage) Sessions(ctx context.Context, userID int64) ([]models.Session, error) {
+	const op = "storage.sqlite.Sessions"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, app_id, device_name, platform, ip, user_agent, created_at, last_seen_at, expires_at, revoked_at
+		FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_seen_at DESC
+	`, userID, time.Now().Unix())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// TouchSession sets the time the session was last seen at.
+func (s *Storage) TouchSession(ctx context.Context, id string, seenAt time.Time) error {
+	const op = "storage.sqlite.TouchSession"
+
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE sessions SET last_seen_at = ? WHERE id = ? AND last_seen_at < ?",
+		seenAt.Unix(), id, seenAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ExtendSession marks the session as seen now and moves its expiration, e.g. when its refresh token is rotated.
+// If there is no such session, returns storage.ErrSessionNotFound.
+func (s *Storage) ExtendSession(ctx context.Context, id string, expiresAt time.Time) error {
+	const op = "storage.sqlite.ExtendSession"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE sessions SET last_seen_at = ?, expires_at = ? WHERE id = ?",
+		time.Now().Unix(), expiresAt.Unix(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+
+	return nil
+}
+
+// RevokeSession revokes the active session of the user and its refresh tokens in one transaction.
+// If the user has no such active session, returns storage.ErrSessionNotFound.
+func (s *Storage) RevokeSession(ctx context.Context, userID int64, id string) error {
+	const op = "storage.sqlite.RevokeSession"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().Unix()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL",
+		now, id, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	revoked, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if revoked == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL",
+		now, id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func scanSession(row rowScanner) (models.Session, error) {
+	var (
+		session                          models.Session
+		createdAt, lastSeenAt, expiresAt int64
+		revokedAt                        sql.NullInt64
+	)
+
+	err := row.Scan(
+		&session.ID, &session.UserID, &session.AppID, &session.DeviceName, &session.Platform, &session.IP,
+		&session.UserAgent, &createdAt, &lastSeenAt, &expiresAt, &revokedAt,
+	)
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	session.CreatedAt = time.Unix(createdAt, 0)
+	session.LastSeenAt = time.Unix(lastSeenAt, 0)
+	session.ExpiresAt = time.Unix(expiresAt, 0)
+	session.Revoked = revokedAt.Valid
+
+	return session, nil
+}
+
+// UserRoles returns the roles of the user sorted by name.
+func (s *Storage) UserRoles(ctx context.Context, userID int64) ([]string, error) {
+	const op = "storage.sqlite.UserRoles"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT role FROM user_roles WHERE user_id = ? ORDER BY role", userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return roles, nil
+}
+
+// SetUserRoles replaces the roles of the user in one transaction.
+// If there is no such user, returns storage.ErrUserNotFound.
+func (s *Storage) SetUserRoles(ctx context.Context, userID int64, roles []string) error {
+	const op = "storage.sqlite.SetUserRoles"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = ?)", userID).Scan(&exists); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !exists {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_roles WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, role := range roles {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO user_roles(user_id, role) VALUES(?, ?)", userID, role); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SaveAuditEvent saves the audit event.
+func (s *Storage) SaveAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	const op = "storage.sqlite.SaveAuditEvent"
+
+	var userID sql.NullInt64
+	if event.UserID != 0 {
+		userID = sql.NullInt64{Int64: event.UserID, Valid: true}
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO audit_events(created_at, event, user_id, phone_hash, ip, user_agent, outcome, reason)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		event.Time.Unix(), event.Event, userID, event.PhoneHash, event.IP, event.UserAgent, event.Outcome, event.Reason,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// AuditEvents returns audit events matching the filter, newest first.
+func (s *Storage) AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	const op = "storage.sqlite.AuditEvents"
+
+	var (
+		conditions []string
+		args       []any
+	)
+
+	if filter.Event != "" {
+		conditions = append(conditions, "event = ?")
+		args = append(args, filter.Event)
+	}
+	if filter.Outcome != "" {
+		conditions = append(conditions, "outcome = ?")
+		args = append(args, filter.Outcome)
+	}
+	if filter.UserID != 0 {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if filter.PhoneHash != "" {
+		conditions = append(conditions, "phone_hash = ?")
+		args = append(args, filter.PhoneHash)
+	}
+	if filter.IP != "" {
+		conditions = append(conditions, "ip = ?")
+		args = append(args, filter.IP)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From.Unix())
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.To.Unix())
+	}
+	if filter.Before != 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, filter.Before)
+	}
+
+	query := "SELECT id, created_at, event, user_id, phone_hash, ip, user_agent, outcome, reason FROM audit_events"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var (
+			event     models.AuditEvent
+			createdAt int64
+			userID    sql.NullInt64
+		)
+
+		err := rows.Scan(
+			&event.ID, &createdAt, &event.Event, &userID, &event.PhoneHash, &event.IP, &event.UserAgent,
+			&event.Outcome, &event.Reason,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		event.Time = time.Unix(createdAt, 0)
+		event.UserID = userID.Int64
+
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
+
+// DeleteAuditEventsBefore deletes audit events older than t and returns how many were deleted.
+func (s *Storage) DeleteAuditEventsBefore(ctx context.Context, t time.Time) (int64, error) {
+	const op = "storage.sqlite.DeleteAuditEventsBefore"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM audit_events WHERE created_at < ?", t.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deleted, nil
+}
+
+// SaveOAuthClient saves the OAuth client. If a client with the same ID exists, returns storage.ErrOAuthClientExists.
+func (s *Storage) SaveOAuthClient(ctx context.Context, client models.OAuthClient) error {
+	const op = "storage.sqlite.SaveOAuthClient"
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO oauth_clients(id, app_id, name, secret_hash, redirect_uris) VALUES(?, ?, ?, ?, ?)
+	`, client.ID, client.AppID, client.Name, client.SecretHash, strings.Join(client.RedirectURIs, " "))
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintPrimaryKey) {
+			return fmt.Errorf("%s: %w", op, storage.ErrOAuthClientExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// OAuthClient returns the OAuth client by ID.
+func (s *Storage) OAuthClient(ctx context.Context, id string) (models.OAuthClient, error) {
+	const op = "storage.sqlite.OAuthClient"
+
+	var (
+		client       models.OAuthClient
+		redirectURIs string
+	)
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, app_id, name, secret_hash, redirect_uris FROM oauth_clients WHERE id = ?
+	`, id).Scan(&client.ID, &client.AppID, &client.Name, &client.SecretHash, &redirectURIs)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.OAuthClient{}, fmt.Errorf("%s: %w", op, storage.ErrOAuthClientNotFound)
+		}
+
+		return models.OAuthClient{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	client.RedirectURIs = strings.Fields(redirectURIs)
+
+	return client, nil
+}
+
+// SaveAuthCode saves the authorization code and deletes expired ones.
+func (s *Storage) SaveAuthCode(ctx context.Context, code models.AuthCode) error {
+	const op = "storage.sqlite.SaveAuthCode"
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO oauth_codes(
+			code_hash, client_id, redirect_uri, user_id, scope, nonce, code_challenge, tokens, auth_time, expires_at
+		)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		code.CodeHash, code.ClientID, code.RedirectURI, code.UserID, code.Scope, code.Nonce, code.CodeChallenge,
+		code.Tokens, code.AuthTime.Unix(), code.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM oauth_codes WHERE expires_at <= ?", time.Now().Unix()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ConsumeAuthCode deletes the authorization code and returns it, so that every code is exchanged only once.
+// If there is no such code, returns storage.ErrAuthCodeNotFound.
+func (s *Storage) ConsumeAuthCode(ctx context.Context, codeHash string) (models.AuthCode, error) {
+	const op = "storage.sqlite.ConsumeAuthCode"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.AuthCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var (
+		code      models.AuthCode
+		authTime  int64
+		expiresAt int64
+	)
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT code_hash, client_id, redirect_uri, user_id, scope, nonce, code_challenge, tokens, auth_time, expires_at
+		FROM oauth_codes WHERE code_hash = ?
+	`, codeHash).Scan(
+		&code.CodeHash, &code.ClientID, &code.RedirectURI, &code.UserID, &code.Scope, &code.Nonce, &code.CodeChallenge,
+		&code.Tokens, &authTime, &expiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.AuthCode{}, fmt.Errorf("%s: %w", op, storage.ErrAuthCodeNotFound)
+		}
+
+		return models.AuthCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM oauth_codes WHERE code_hash = ?", codeHash)
+	if err != nil {
+		return models.AuthCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return models.AuthCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if deleted == 0 {
+		return models.AuthCode{}, fmt.Errorf("%s: %w", op, storage.ErrAuthCodeNotFound)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.AuthCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	code.AuthTime = time.Unix(authTime, 0)
+	code.ExpiresAt = time.Unix(expiresAt, 0)
+
+	return code, nil
+}
+
+// SaveAPIKey saves the API key and returns its ID.
+func (s *Storage) SaveAPIKey(ctx context.Context, key models.APIKey) (int64, error) {
+	const op = "storage.sqlite.SaveAPIKey"
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO api_keys(app_id, name, prefix, key_hash, scopes, created_at) VALUES(?, ?, ?, ?, ?, ?)
+	`, key.AppID, key.Name, key.Prefix, key.KeyHash, strings.Join(key.Scopes, " "), key.CreatedAt.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// APIKey returns the API key with the given hash.
+func (s *Storage) APIKey(ctx context.Context, keyHash string) (models.APIKey, error) {
+	const op = "storage.sqlite.APIKey"
+
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, `
+		SELECT id, app_id, name, prefix, key_hash, scopes, created_at, last_used_at FROM api_keys WHERE key_hash = ?
+	`, keyHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.APIKey{}, fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+		}
+
+		return models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// APIKeys returns API keys of the app, or of all apps if appID is zero, oldest first.
+func (s *Storage) APIKeys(ctx context.Context, appID int64) ([]models.APIKey, error) {
+	const op = "storage.sqlite.APIKeys"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, app_id, name, prefix, key_hash, scopes, created_at, last_used_at
+		FROM api_keys
+		WHERE ? = 0 OR app_id = ?
+		ORDER BY id
+	`, appID, appID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// TouchAPIKey sets the time the API key was last used at.
+func (s *Storage) TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error {
+	const op = "storage.sqlite.TouchAPIKey"
+
+	_, err := s.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = ? WHERE id = ?", usedAt.Unix(), id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteAPIKey deletes the API key, so that it is no longer accepted.
+func (s *Storage) DeleteAPIKey(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.DeleteAPIKey"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM api_keys WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
+
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	var (
+		key        models.APIKey
+		scopes     string
+		createdAt  int64
+		lastUsedAt sql.NullInt64
+	)
+
+	err := row.Scan(&key.ID, &key.AppID, &key.Name, &key.Prefix, &key.KeyHash, &scopes, &createdAt, &lastUsedAt)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+	key.CreatedAt = time.Unix(createdAt, 0)
+	if lastUsedAt.Valid {
+		usedAt := time.Unix(lastUsedAt.Int64, 0)
+		key.LastUsedAt = &usedAt
+	}
+
+	return key, nil
+}
+
+// SaveAuthor saves a new author. It shows up in the catalog of every user, not in favorites.
+// Returns the stored author. If the clip of the author doesn't exist, returns storage.ErrClipNotFound.
+func (s *Storage) SaveAuthor(ctx context.Context, author models.Author) (models.Author, error) {
+	const op = "storage.sqlite.SaveAuthor"
+
+	saved, err := saveCatalogEntry(ctx, s.db, authorCatalog, author.Clip,
+		"INSERT INTO author(name, image, clip) VALUES(?, ?, ?)",
+		author.Name, author.Image, author.Clip,
+	)
+	if err != nil {
+		return models.Author{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// UpdateAuthor replaces the author and returns the stored one.
+// If there is no such author, returns storage.ErrContentNotFound.
+// If the clip of the author doesn't exist, returns storage.ErrClipNotFound.
+func (s *Storage) UpdateAuthor(ctx context.Context, author models.Author) (models.Author, error) {
+	const op = "storage.sqlite.UpdateAuthor"
+
+	saved, err := updateCatalogEntry(ctx, s.db, authorCatalog, author.ID, author.Clip,
+		"UPDATE author SET name = ?, image = ?, clip = ? WHERE id = ?",
+		author.Name, author.Image, author.Clip, author.ID,
+	)
+	if err != nil {
+		return models.Author{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// DeleteAuthor deletes the author from the catalog and from favorites of every user.
+// If there is no such author, returns storage.ErrContentNotFound.
+func (s *Storage) DeleteAuthor(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.DeleteAuthor"
+
+	if err := deleteCatalogEntry(ctx, s.db, authorCatalog, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SavePoet saves a new poet. It shows up in the catalog of every user, not in favorites.
+// Returns the stored poet. If the clip of the poet doesn't exist, returns storage.ErrClipNotFound.
+func (s *Storage) SavePoet(ctx context.Context, poet models.Poet) (models.Poet, error) {
+	const op = "storage.sqlite.SavePoet"
+
+	saved, err := saveCatalogEntry(ctx, s.db, poetCatalog, poet.Clip,
+		"INSERT INTO poet(name, image, clip) VALUES(?, ?, ?)",
+		poet.Name, poet.Image, poet.Clip,
+	)
+	if err != nil {
+		return models.Poet{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// UpdatePoet replaces the poet and returns the stored one.
+// If there is no such poet, returns storage.ErrContentNotFound.
+// If the clip of the poet doesn't exist, returns storage.ErrClipNotFound.
+func (s *Storage) UpdatePoet(ctx context.Context, poet models.Poet) (models.Poet, error) {
+	const op = "storage.sqlite.UpdatePoet"
+
+	saved, err := updateCatalogEntry(ctx, s.db, poetCatalog, poet.ID, poet.Clip,
+		"UPDATE poet SET name = ?, image = ?, clip = ? WHERE id = ?",
+		poet.Name, poet.Image, poet.Clip, poet.ID,
+	)
+	if err != nil {
+		return models.Poet{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// DeletePoet deletes the poet from the catalog and from favorites of every user.
+// If there is no such poet, returns storage.ErrContentNotFound.
+func (s *Storage) DeletePoet(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.DeletePoet"
+
+	if err := deleteCatalogEntry(ctx, s.db, poetCatalog, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SaveArticle saves a new article. It shows up in the catalog of every user, not in favorites.
+// Returns the stored article. If the clip of the article doesn't exist, returns storage.ErrClipNotFound.
+func (s *Storage) SaveArticle(ctx context.Context, article models.Article) (models.Article, error) {
+	const op = "storage.sqlite.SaveArticle"
+
+	saved, err := saveCatalogEntry(ctx, s.db, articleCatalog, article.Clip,
+		"INSERT INTO article(name, description, image, clip) VALUES(?, ?, ?, ?)",
+		article.Name, article.Description, article.Image, article.Clip,
+	)
+	if err != nil {
+		return models.Article{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// UpdateArticle replaces the article and returns the stored one.
+// If there is no such article, returns storage.ErrContentNotFound.
+// If the clip of the article doesn't exist, returns storage.ErrClipNotFound.
+func (s *Storage) UpdateArticle(ctx context.Context, article models.Article) (models.Article, error) {
+	const op = "storage.sqlite.UpdateArticle"
+
+	saved, err := updateCatalogEntry(ctx, s.db, articleCatalog, article.ID, article.Clip,
+		"UPDATE article SET name = ?, description = ?, image = ?, clip = ? WHERE id = ?",
+		article.Name, article.Description, article.Image, article.Clip, article.ID,
+	)
+	if err != nil {
+		return models.Article{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// DeleteArticle deletes the article from the catalog and from favorites of every user.
+// If there is no such article, returns storage.ErrContentNotFound.
+func (s *Storage) DeleteArticle(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.DeleteArticle"
+
+	if err := deleteCatalogEntry(ctx, s.db, articleCatalog, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// favoriteCatalog describes a catalog table whose entries users keep in favorites.
+type favoriteCatalog struct {
+	// table holds the entries.
+	table string
+	// favorites holds a row per user and favorite entry, with the entry ID in favoriteColumn.
+	favorites      string
+	favoriteColumn string
+}
+
+// catalog is a favoriteCatalog with entries of type T.
+type catalog[T any] struct {
+	favoriteCatalog
+	// get reads the entry by ID.
+	get func(ctx context.Context, q querier, id int64) (T, error)
+}
+
+var (
+	authorCatalog = catalog[models.Author]{
+		favoriteCatalog: favoriteCatalog{
+			table:          "author",
+			favorites:      "authors",
+			favoriteColumn: "authorId",
+		},
+		get: func(ctx context.Context, q querier, id int64) (models.Author, error) {
+			var author models.Author
+			err := q.QueryRowContext(ctx, "SELECT id, name, image, clip FROM author WHERE id = ?", id).
+				Scan(&author.ID, &author.Name, &author.Image, &author.Clip)
+			return author, err
+		},
+	}
+	poetCatalog = catalog[models.Poet]{
+		favoriteCatalog: favoriteCatalog{
+			table:          "poet",
+			favorites:      "poets",
+			favoriteColumn: "poetId",
+		},
+		get: func(ctx context.Context, q querier, id int64) (models.Poet, error) {
+			var poet models.Poet
+			err := q.QueryRowContext(ctx, "SELECT id, name, image, clip FROM poet WHERE id = ?", id).
+				Scan(&poet.ID, &poet.Name, &poet.Image, &poet.Clip)
+			return poet, err
+		},
+	}
+	articleCatalog = catalog[models.Article]{
+		favoriteCatalog: favoriteCatalog{
+			table:          "article",
+			favorites:      "articles",
+			favoriteColumn: "articleId",
+		},
+		get: func(ctx context.Context, q querier, id int64) (models.Article, error) {
+			var article models.Article
+			err := q.QueryRowContext(ctx, "SELECT id, name, description, image, clip FROM article WHERE id = ?", id).
+				Scan(&article.ID, &article.Name, &article.Description, &article.Image, &article.Clip)
+			return article, err
+		},
+	}
+)
+
+// favoriteCatalogs maps the types of content users keep in favorites to their catalogs.
+var favoriteCatalogs = map[string]favoriteCatalog{
+	models.FavoriteTypeAuthor:  authorCatalog.favoriteCatalog,
+	models.FavoriteTypePoet:    poetCatalog.favoriteCatalog,
+	models.FavoriteTypeArticle: articleCatalog.favoriteCatalog,
+}
+
+// catalogQuery builds the query for a page of the catalog matching the filter. The query selects the columns
+// of the entries, aliased as c, followed by whether the entry is in favorites of the user.
+func catalogQuery(f favoriteCatalog, columns string, userID int64, filter models.CatalogFilter) (string, []any) {
+	var conditions []string
+	args := []any{userID}
+
+	if filter.IsFave != nil {
+		if *filter.IsFave {
+			conditions = append(conditions, "f.userId IS NOT NULL")
+		} else {
+			conditions = append(conditions, "f.userId IS NULL")
+		}
+	}
+
+	order, compare := "ASC", ">"
+	if filter.Descending {
+		order, compare = "DESC", "<"
+	}
+
+	if filter.After != nil {
+		if filter.SortByName {
+			conditions = append(conditions, fmt.Sprintf("(c.name, c.id) %s (?, ?)", compare))
+			args = append(args, filter.After.Name, filter.After.ID)
+		} else {
+			conditions = append(conditions, fmt.Sprintf("c.id %s ?", compare))
+			args = append(args, filter.After.ID)
+		}
+	}
+
+	query := fmt.Sprintf(
+		"SELECT %s, f.userId IS NOT NULL FROM %s AS c LEFT JOIN %s AS f ON f.%s = c.id AND f.userId = ?",
+		columns, f.table, f.favorites, f.favoriteColumn,
+	)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if filter.SortByName {
+		query += fmt.Sprintf(" ORDER BY c.name %[1]s, c.id %[1]s", order)
+	} else {
+		query += fmt.Sprintf(" ORDER BY c.id %s", order)
+	}
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	return query, args
+}
+
+// saveCatalogEntry inserts an entry with the insert query and returns the stored entry in one transaction.
+func saveCatalogEntry[T any](
+	ctx context.Context,
+	db *sql.DB,
+	c catalog[T],
+	clipID int64,
+	insert string,
+	args ...any,
+) (T, error) {
+	var zero T
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return zero, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := clipExists(ctx, tx, clipID); err != nil {
+		return zero, err
+	}
+
+	res, err := tx.ExecContext(ctx, insert, args...)
+	if err != nil {
+		return zero, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return zero, err
+	}
+
+	saved, err := c.get(ctx, tx, id)
+	if err != nil {
+		return zero, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return zero, err
+	}
+
+	return saved, nil
+}
+
+// updateCatalogEntry updates the entry with the update query and returns the stored entry in one transaction.
+func updateCatalogEntry[T any](
+	ctx context.Context,
+	db *sql.DB,
+	c catalog[T],
+	id int64,
+	clipID int64,
+	update string,
+	args ...any,
+) (T, error) {
+	var zero T
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return zero, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := clipExists(ctx, tx, clipID); err != nil {
+		return zero, err
+	}
+
+	res, err := tx.ExecContext(ctx, update, args...)
+	if err != nil {
+		return zero, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return zero, err
+	}
+	if affected == 0 {
+		return zero, storage.ErrContentNotFound
+	}
+
+	saved, err := c.get(ctx, tx, id)
+	if err != nil {
+		return zero, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return zero, err
+	}
+
+	return saved, nil
+}
+
+// deleteCatalogEntry deletes the entry and removes it from favorites of every user in one transaction.
+func deleteCatalogEntry[T any](ctx context.Context, db *sql.DB, c catalog[T], id int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = ?", c.table), id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return storage.ErrContentNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = ?", c.favorites, c.favoriteColumn), id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// clipExists returns storage.ErrClipNotFound if there is no clip with the ID.
+func clipExists(ctx context.Context, q querier, clipID int64) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM clip WHERE id = ?)", clipID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return storage.ErrClipNotFound
+	}
+
+	return nil
+}
+
+// SaveClip saves a new clip with its text and returns the stored clip.
+// The clip has no quiz until one is saved with the ID of the clip.
+func (s *Storage) SaveClip(ctx context.Context, clip models.Clip) (models.Clip, error) {
+	const op = "storage.sqlite.SaveClip"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO clip(image) VALUES(?)", clip.Image)
+	if err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	clip.ID, err = res.LastInsertId()
+	if err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := insertClipText(ctx, tx, clip); err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := getClip(ctx, tx, clip.ID)
+	if err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// UpdateClip replaces the image and the text of the clip and returns the stored clip. Its quiz is kept.
+// If there is no such clip, returns storage.ErrContentNotFound.
+func (s *Storage) UpdateClip(ctx context.Context, clip models.Clip) (models.Clip, error) {
+	const op = "storage.sqlite.UpdateClip"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, "UPDATE clip SET image = ? WHERE id = ?", clip.Image, clip.ID)
+	if err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, storage.ErrContentNotFound)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM clipText WHERE clipTextId = ?", clip.ID); err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := insertClipText(ctx, tx, clip); err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := getClip(ctx, tx, clip.ID)
+	if err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Clip{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// DeleteClip deletes the clip with its text and quiz.
+// If there is no such clip, returns storage.ErrContentNotFound.
+// If an author, a poet or an article refers to the clip, returns storage.ErrContentInUse.
+func (s *Storage) DeleteClip(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.DeleteClip"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var inUse bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM author WHERE clip = ?)
+		    OR EXISTS(SELECT 1 FROM poet WHERE clip = ?)
+		    OR EXISTS(SELECT 1 FROM article WHERE clip = ?)
+	`, id, id, id).Scan(&inUse)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if inUse {
+		return fmt.Errorf("%s: %w", op, storage.ErrContentInUse)
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM clip WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrContentNotFound)
+	}
+
+	for _, query := range []string{
+		"DELETE FROM clipText WHERE clipTextId = ?",
+		"DELETE FROM answers WHERE id = ?",
+		"DELETE FROM quiz WHERE id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// insertClipText inserts the text sections of the clip in their order.
+func insertClipText(ctx context.Context, tx *sql.Tx, clip models.Clip) error {
+	for _, text := range clip.Text {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO clipText(clipTextId, title, text) VALUES(?, ?, ?)",
+			clip.ID, text.Title, text.Text,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SaveQuiz saves the quiz of the clip with the quiz ID, with its answers, and returns the stored quiz.
+// If there is no such clip, returns storage.ErrClipNotFound.
+// If the clip has a quiz already, returns storage.ErrQuizExists.
+func (s *Storage) SaveQuiz(ctx context.Context, quiz models.Quiz) (models.Quiz, error) {
+	const op = "storage.sqlite.SaveQuiz"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := clipExists(ctx, tx, quiz.ID); err != nil {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO quiz(id, question, description, image) VALUES(?, ?, ?, ?)",
+		quiz.ID, quiz.Question, quiz.Description, quiz.Image,
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && (errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintPrimaryKey) ||
+			errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique)) {
+			return models.Quiz{}, fmt.Errorf("%s: %w", op, storage.ErrQuizExists)
+		}
+
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := insertAnswers(ctx, tx, quiz); err != nil {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := getQuiz(ctx, tx, quiz.ID)
+	if err != nil {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// UpdateQuiz replaces the quiz with its answers and returns the stored quiz.
+// If there is no such quiz, returns storage.ErrContentNotFound.
+func (s *Storage) UpdateQuiz(ctx context.Context, quiz models.Quiz) (models.Quiz, error) {
+	const op = "storage.sqlite.UpdateQuiz"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE quiz SET question = ?, description = ?, image = ? WHERE id = ?",
+		quiz.Question, quiz.Description, quiz.Image, quiz.ID,
+	)
+	if err != nil {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, storage.ErrContentNotFound)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM answers WHERE id = ?", quiz.ID); err != nil {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := insertAnswers(ctx, tx, quiz); err != nil {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := getQuiz(ctx, tx, quiz.ID)
+	if err != nil {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Quiz{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// DeleteQuiz deletes the quiz with its answers. If there is no such quiz, returns storage.ErrContentNotFound.
+func (s *Storage) DeleteQuiz(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.DeleteQuiz"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM quiz WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrContentNotFound)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM answers WHERE id = ?", id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// insertAnswers inserts the answers of the quiz in their order.
+func insertAnswers(ctx context.Context, tx *sql.Tx, quiz models.Quiz) error {
+	for _, answer := range quiz.Answers {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO answers(id, text, isRight) VALUES(?, ?, ?)",
+			quiz.ID, answer.Text, answer.IsRight,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
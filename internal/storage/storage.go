@@ -3,7 +3,28 @@ package storage
 import "errors"
 
 var (
-	ErrUserExists   = errors.New("user already exists")
-	ErrUserNotFound = errors.New("user not found")
-	ErrAppNotFound  = errors.New("app not found")
+	ErrUserExists           = errors.New("user already exists")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrUserNotDeleted       = errors.New("user is not deleted")
+	ErrAppNotFound          = errors.New("app not found")
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenUsed     = errors.New("refresh token already used")
+	ErrSigningKeyNotFound   = errors.New("signing key not found")
+	ErrOTPNotFound          = errors.New("one-time code not found")
+	ErrOTPAttemptsExhausted = errors.New("one-time code attempts exhausted")
+	ErrTOTPNotFound         = errors.New("totp not found")
+	ErrTOTPExists           = errors.New("totp already confirmed")
+	ErrTOTPStepUsed         = errors.New("totp code already used")
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+	ErrChallengeNotFound    = errors.New("two-factor challenge not found")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrOAuthClientNotFound  = errors.New("oauth client not found")
+	ErrOAuthClientExists    = errors.New("oauth client already exists")
+	ErrAuthCodeNotFound     = errors.New("authorization code not found")
+	ErrAPIKeyNotFound       = errors.New("api key not found")
+	ErrContentNotFound      = errors.New("content not found")
+	ErrContentInUse         = errors.New("content is in use")
+	ErrClipNotFound         = errors.New("clip not found")
+	ErrQuizNotFound         = errors.New("quiz not found")
+	ErrQuizExists           = errors.New("quiz already exists")
 )
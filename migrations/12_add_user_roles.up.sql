@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_roles
+(
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role    TEXT    NOT NULL,
+    PRIMARY KEY (user_id, role)
+);
+
+INSERT OR IGNORE INTO user_roles (user_id, role)
+SELECT id, 'user'
+FROM users;
@@ -0,0 +1,21 @@
+DELETE FROM users WHERE phone IS NULL;
+
+CREATE TABLE IF NOT EXISTS users_old
+(
+    id                  INTEGER PRIMARY KEY,
+    phone               TEXT    NOT NULL UNIQUE,
+    pass_hash           BLOB    NOT NULL,
+    name                TEXT    NOT NULL,
+    image               TEXT    NOT NULL,
+    two_factor_required INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO users_old (id, phone, pass_hash, name, image, two_factor_required)
+SELECT id, phone, pass_hash, name, image, two_factor_required
+FROM users;
+
+DROP TABLE users;
+
+ALTER TABLE users_old RENAME TO users;
+
+CREATE INDEX IF NOT EXISTS idx_phone ON users (phone);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS users_new
+(
+    id                  INTEGER PRIMARY KEY,
+    phone               TEXT UNIQUE,
+    pass_hash           BLOB    NOT NULL,
+    name                TEXT    NOT NULL,
+    image               TEXT    NOT NULL,
+    two_factor_required INTEGER NOT NULL DEFAULT 0,
+    device_id           TEXT UNIQUE
+);
+
+INSERT INTO users_new (id, phone, pass_hash, name, image, two_factor_required)
+SELECT id, phone, pass_hash, name, image, two_factor_required
+FROM users;
+
+DROP TABLE users;
+
+ALTER TABLE users_new RENAME TO users;
+
+CREATE INDEX IF NOT EXISTS idx_phone ON users (phone);
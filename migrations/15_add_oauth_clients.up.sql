@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS oauth_clients
+(
+    id            TEXT PRIMARY KEY,
+    app_id        INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    name          TEXT    NOT NULL,
+    secret_hash   TEXT    NOT NULL DEFAULT '',
+    redirect_uris TEXT    NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS oauth_codes
+(
+    code_hash      TEXT PRIMARY KEY,
+    client_id      TEXT    NOT NULL REFERENCES oauth_clients (id) ON DELETE CASCADE,
+    redirect_uri   TEXT    NOT NULL,
+    user_id        INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    scope          TEXT    NOT NULL,
+    nonce          TEXT    NOT NULL,
+    code_challenge TEXT    NOT NULL,
+    tokens         BLOB    NOT NULL,
+    auth_time      INTEGER NOT NULL,
+    expires_at     INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_oauth_codes_expires_at ON oauth_codes (expires_at);
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS articles;
+DROP TABLE IF EXISTS poets;
+DROP TABLE IF EXISTS authors;
+DROP TABLE IF EXISTS article;
+DROP TABLE IF EXISTS poet;
+DROP TABLE IF EXISTS author;
+DROP TABLE IF EXISTS answers;
+DROP TABLE IF EXISTS quiz;
+DROP TABLE IF EXISTS clipText;
+DROP TABLE IF EXISTS clip;
@@ -0,0 +1,78 @@
+CREATE TABLE IF NOT EXISTS clip
+(
+    id    INTEGER PRIMARY KEY,
+    image TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS clipText
+(
+    clipTextId INTEGER NOT NULL REFERENCES clip (id) ON DELETE CASCADE,
+    title      TEXT    NOT NULL,
+    text       TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_clipText_clipTextId ON clipText (clipTextId);
+
+CREATE TABLE IF NOT EXISTS quiz
+(
+    id          INTEGER PRIMARY KEY REFERENCES clip (id) ON DELETE CASCADE,
+    question    TEXT NOT NULL,
+    description TEXT NOT NULL,
+    image       TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS answers
+(
+    id      INTEGER NOT NULL REFERENCES quiz (id) ON DELETE CASCADE,
+    text    TEXT    NOT NULL,
+    isRight INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_answers_id ON answers (id);
+
+CREATE TABLE IF NOT EXISTS author
+(
+    id    INTEGER PRIMARY KEY,
+    name  TEXT    NOT NULL,
+    image TEXT    NOT NULL,
+    clip  INTEGER NOT NULL REFERENCES clip (id)
+);
+
+CREATE TABLE IF NOT EXISTS poet
+(
+    id    INTEGER PRIMARY KEY,
+    name  TEXT    NOT NULL,
+    image TEXT    NOT NULL,
+    clip  INTEGER NOT NULL REFERENCES clip (id)
+);
+
+CREATE TABLE IF NOT EXISTS article
+(
+    id          INTEGER PRIMARY KEY,
+    name        TEXT    NOT NULL,
+    description TEXT    NOT NULL,
+    image       TEXT    NOT NULL,
+    clip        INTEGER NOT NULL REFERENCES clip (id)
+);
+
+CREATE TABLE IF NOT EXISTS authors
+(
+    userId   INTEGER NOT NULL,
+    authorId INTEGER NOT NULL,
+    isFave   INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (userId, authorId)
+);
+
+CREATE TABLE IF NOT EXISTS poets
+(
+    userId INTEGER NOT NULL,
+    poetId INTEGER NOT NULL,
+    isFave INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (userId, poetId)
+);
+
+CREATE TABLE IF NOT EXISTS articles
+(
+    userId    INTEGER NOT NULL,
+    articleId INTEGER NOT NULL,
+    isFave    INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (userId, articleId)
+);
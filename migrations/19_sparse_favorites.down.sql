@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS authors_dense
+(
+    userId   INTEGER NOT NULL,
+    authorId INTEGER NOT NULL,
+    isFave   INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (userId, authorId)
+);
+INSERT INTO authors_dense (userId, authorId, isFave)
+SELECT u.id, c.id, EXISTS(SELECT 1 FROM authors AS f WHERE f.userId = u.id AND f.authorId = c.id)
+FROM users AS u
+         CROSS JOIN author AS c;
+DROP TABLE authors;
+ALTER TABLE authors_dense RENAME TO authors;
+
+CREATE TABLE IF NOT EXISTS poets_dense
+(
+    userId INTEGER NOT NULL,
+    poetId INTEGER NOT NULL,
+    isFave INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (userId, poetId)
+);
+INSERT INTO poets_dense (userId, poetId, isFave)
+SELECT u.id, c.id, EXISTS(SELECT 1 FROM poets AS f WHERE f.userId = u.id AND f.poetId = c.id)
+FROM users AS u
+         CROSS JOIN poet AS c;
+DROP TABLE poets;
+ALTER TABLE poets_dense RENAME TO poets;
+
+CREATE TABLE IF NOT EXISTS articles_dense
+(
+    userId    INTEGER NOT NULL,
+    articleId INTEGER NOT NULL,
+    isFave    INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (userId, articleId)
+);
+INSERT INTO articles_dense (userId, articleId, isFave)
+SELECT u.id, c.id, EXISTS(SELECT 1 FROM articles AS f WHERE f.userId = u.id AND f.articleId = c.id)
+FROM users AS u
+         CROSS JOIN article AS c;
+DROP TABLE articles;
+ALTER TABLE articles_dense RENAME TO articles;
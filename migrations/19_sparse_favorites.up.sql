@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS authors_sparse
+(
+    userId   INTEGER NOT NULL,
+    authorId INTEGER NOT NULL,
+    PRIMARY KEY (userId, authorId)
+);
+INSERT INTO authors_sparse (userId, authorId)
+SELECT DISTINCT userId, authorId
+FROM authors
+WHERE isFave = 1;
+DROP TABLE authors;
+ALTER TABLE authors_sparse RENAME TO authors;
+
+CREATE TABLE IF NOT EXISTS poets_sparse
+(
+    userId INTEGER NOT NULL,
+    poetId INTEGER NOT NULL,
+    PRIMARY KEY (userId, poetId)
+);
+INSERT INTO poets_sparse (userId, poetId)
+SELECT DISTINCT userId, poetId
+FROM poets
+WHERE isFave = 1;
+DROP TABLE poets;
+ALTER TABLE poets_sparse RENAME TO poets;
+
+CREATE TABLE IF NOT EXISTS articles_sparse
+(
+    userId    INTEGER NOT NULL,
+    articleId INTEGER NOT NULL,
+    PRIMARY KEY (userId, articleId)
+);
+INSERT INTO articles_sparse (userId, articleId)
+SELECT DISTINCT userId, articleId
+FROM articles
+WHERE isFave = 1;
+DROP TABLE articles;
+ALTER TABLE articles_sparse RENAME TO articles;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_authors_user_favorited_at;
+DROP INDEX IF EXISTS idx_poets_user_favorited_at;
+DROP INDEX IF EXISTS idx_articles_user_favorited_at;
+
+ALTER TABLE authors DROP COLUMN favorited_at;
+ALTER TABLE poets DROP COLUMN favorited_at;
+ALTER TABLE articles DROP COLUMN favorited_at;
@@ -0,0 +1,12 @@
+ALTER TABLE authors ADD COLUMN favorited_at INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE poets ADD COLUMN favorited_at INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE articles ADD COLUMN favorited_at INTEGER NOT NULL DEFAULT 0;
+
+-- The time existing favorites were added is unknown, so they count as added now.
+UPDATE authors SET favorited_at = strftime('%s', 'now');
+UPDATE poets SET favorited_at = strftime('%s', 'now');
+UPDATE articles SET favorited_at = strftime('%s', 'now');
+
+CREATE INDEX IF NOT EXISTS idx_authors_user_favorited_at ON authors (userId, favorited_at);
+CREATE INDEX IF NOT EXISTS idx_poets_user_favorited_at ON poets (userId, favorited_at);
+CREATE INDEX IF NOT EXISTS idx_articles_user_favorited_at ON articles (userId, favorited_at);
@@ -0,0 +1,2 @@
+UPDATE revoked_users SET revoked_at_ms = revoked_at_ms / 1000;
+ALTER TABLE revoked_users RENAME COLUMN revoked_at_ms TO revoked_at;
@@ -0,0 +1,4 @@
+-- Revocation times are kept in milliseconds to tell tokens issued before a revocation in the same second
+-- from those issued after it.
+ALTER TABLE revoked_users RENAME COLUMN revoked_at TO revoked_at_ms;
+UPDATE revoked_users SET revoked_at_ms = revoked_at_ms * 1000;
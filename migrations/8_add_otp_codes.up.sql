@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS otp_codes
+(
+    phone      TEXT    NOT NULL,
+    purpose    TEXT    NOT NULL,
+    code_hash  TEXT    NOT NULL,
+    expires_at INTEGER NOT NULL,
+    sent_at    INTEGER NOT NULL,
+    attempts   INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (phone, purpose)
+);
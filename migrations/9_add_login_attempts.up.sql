@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS login_attempts
+(
+    kind            TEXT    NOT NULL,
+    subject         TEXT    NOT NULL,
+    failures        INTEGER NOT NULL DEFAULT 0,
+    last_failure_at INTEGER NOT NULL,
+    blocked_until   INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (kind, subject)
+);
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	ssov1 "github.com/DenisPopkov/protos/gen/go/sso"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"path/filepath"
+	"sso/internal/domain/models"
+	"sso/internal/lib/secret"
+	"sso/internal/services/apikeys"
+	"sso/internal/storage/sqlite"
+	"sso/tests/suite"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys_AdminManagesKeys(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	adminPhone := randomFakePhone()
+	adminPass := randomFakePassword()
+
+	respAdmin, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    adminPhone,
+		Password: adminPass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	grantRoles(t, st, respAdmin.GetUserId(), models.RoleAdmin, models.RoleUser)
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    adminPhone,
+		Password: adminPass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+	adminToken := respLogin.GetToken()
+
+	resp := restRequest(t, st, http.MethodPost, "/admin/api-keys", adminToken, map[string]any{
+		"appId":  appID,
+		"name":   "analytics",
+		"scopes": []string{models.PermissionManageAPIKeys},
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodPost, "/admin/api-keys", adminToken, map[string]any{
+		"appId":  appID,
+		"name":   "analytics",
+		"scopes": []string{models.PermissionViewAudit},
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var created struct {
+		ID     int64    `json:"id"`
+		Prefix string   `json:"prefix"`
+		Scopes []string `json:"scopes"`
+		Key    string   `json:"key"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Equal(t, []string{models.PermissionViewAudit}, created.Scopes)
+	assert.Contains(t, created.Key, created.Prefix)
+
+	// The key is accepted only on the routes its scopes allow.
+	resp = restRequest(t, st, http.MethodGet, "/admin/audit", created.Key, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodGet, fmt.Sprintf("/admin/users/%d/roles", respAdmin.GetUserId()), created.Key, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodGet, "/sessions", created.Key, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodGet, "/admin/api-keys", created.Key, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodGet, fmt.Sprintf("/admin/api-keys?appId=%d", appID), adminToken, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var keys []models.APIKey
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&keys))
+	assert.Condition(t, func() bool {
+		for _, key := range keys {
+			if key.ID == created.ID {
+				return key.LastUsedAt != nil
+			}
+		}
+		return false
+	})
+
+	resp = restRequest(t, st, http.MethodDelete, fmt.Sprintf("/admin/api-keys/%d", created.ID), adminToken, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodGet, "/admin/audit", created.Key, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestAPIKeys_GRPC(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    randomFakePhone(),
+		Password: randomFakePassword(),
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	req := &ssov1.GetUserRequest{UserId: respReg.GetUserId()}
+
+	_, err = st.AuthClient.GetUser(ctx, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = st.AuthClient.GetUser(withCredential(ctx, apikeys.Prefix+gofakeit.LetterN(43)), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = st.AuthClient.GetUser(withCredential(ctx, createAPIKey(t, st, models.PermissionViewAudit)), req)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AuthClient.GetUser(withCredential(ctx, createAPIKey(t, st, models.PermissionReadUsers)), req)
+	assert.NoError(t, err)
+}
+
+// createAPIKey saves an API key of the test app with the scopes directly in the storage and returns the key.
+func createAPIKey(t *testing.T, st *suite.Suite, scopes ...string) string {
+	t.Helper()
+
+	storage, err := sqlite.New(filepath.Join("..", st.Cfg.StoragePath))
+	require.NoError(t, err)
+	defer storage.Stop()
+
+	key := apikeys.Prefix + gofakeit.LetterN(43)
+
+	_, err = storage.SaveAPIKey(context.Background(), models.APIKey{
+		AppID:     appID,
+		Name:      "Test service",
+		Prefix:    key[:12],
+		KeyHash:   secret.Hash(key),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+
+	return key
+}
+
+// withCredential returns the context to call RPCs meant for services with an API key or a user token.
+func withCredential(ctx context.Context, credential string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+credential)
+}
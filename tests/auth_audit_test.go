@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"encoding/json"
+	ssov1 "github.com/DenisPopkov/protos/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/url"
+	"sso/internal/domain/models"
+	"sso/tests/suite"
+	"testing"
+)
+
+func TestAudit_LoginEvents(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: randomFakePassword(),
+		AppId:    appID,
+	})
+	require.Error(t, err)
+
+	adminPhone := randomFakePhone()
+	adminPass := randomFakePassword()
+
+	respAdmin, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    adminPhone,
+		Password: adminPass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	grantRoles(t, st, respAdmin.GetUserId(), models.RoleAdmin, models.RoleUser)
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    adminPhone,
+		Password: adminPass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	query := url.Values{"phone": {phone}}
+	resp := restRequest(t, st, http.MethodGet, "/admin/audit?"+query.Encode(), respLogin.GetToken(), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var page struct {
+		Events []models.AuditEvent `json:"events"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	require.Len(t, page.Events, 2)
+
+	// Newest events come first.
+	assert.Equal(t, models.AuditEventLogin, page.Events[0].Event)
+	assert.Equal(t, models.AuditOutcomeFailure, page.Events[0].Outcome)
+	assert.Equal(t, "invalid_password", page.Events[0].Reason)
+	assert.Equal(t, respReg.GetUserId(), page.Events[0].UserID)
+	assert.NotEmpty(t, page.Events[0].PhoneHash)
+	assert.NotEmpty(t, page.Events[0].IP)
+
+	assert.Equal(t, models.AuditEventRegister, page.Events[1].Event)
+	assert.Equal(t, models.AuditOutcomeSuccess, page.Events[1].Outcome)
+
+	query = url.Values{"phone": {phone}, "limit": {"1"}}
+	resp = restRequest(t, st, http.MethodGet, "/admin/audit?"+query.Encode(), respLogin.GetToken(), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var firstPage struct {
+		Events     []models.AuditEvent `json:"events"`
+		NextBefore int64               `json:"nextBefore"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&firstPage))
+	require.Len(t, firstPage.Events, 1)
+	assert.Equal(t, page.Events[0].ID, firstPage.NextBefore)
+
+	resp = restRequest(t, st, http.MethodGet, "/admin/audit?limit=1000", respLogin.GetToken(), nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodGet, "/admin/audit?from=yesterday", respLogin.GetToken(), nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAudit_NotAdmin(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	resp := restRequest(t, st, http.MethodGet, "/admin/audit", respLogin.GetToken(), nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
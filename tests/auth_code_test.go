@@ -0,0 +1,261 @@
+package tests
+
+import (
+	"bufio"
+	ssov1 "github.com/DenisPopkov/protos/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"os"
+	"path/filepath"
+	"sso/internal/config"
+	"sso/tests/suite"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVerifyCode_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+
+	_, err := st.AuthClient.RequestCode(ctx, &ssov1.RequestCodeRequest{Phone: phone})
+	require.NoError(t, err)
+
+	code := sentCode(t, st, phone)
+
+	respVerify, err := st.AuthClient.VerifyCode(ctx, &ssov1.VerifyCodeRequest{
+		Phone: phone,
+		Code:  code,
+		AppId: appID,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, respVerify.GetToken())
+	assert.NotEmpty(t, respVerify.GetRefreshToken())
+
+	// Every code is accepted only once.
+	_, err = st.AuthClient.VerifyCode(ctx, &ssov1.VerifyCodeRequest{
+		Phone: phone,
+		Code:  code,
+		AppId: appID,
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid code")
+
+	// The account registered with a code has no password.
+	_, err = st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: randomFakePassword(),
+		AppId:    appID,
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "user already exists")
+}
+
+func TestRequestCode_ResendCooldown(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+
+	_, err := st.AuthClient.RequestCode(ctx, &ssov1.RequestCodeRequest{Phone: phone})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.RequestCode(ctx, &ssov1.RequestCodeRequest{Phone: phone})
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestVerifyCode_TooManyAttempts(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+
+	_, err := st.AuthClient.RequestCode(ctx, &ssov1.RequestCodeRequest{Phone: phone})
+	require.NoError(t, err)
+
+	code := sentCode(t, st, phone)
+
+	for i := 0; i < st.Cfg.OTP.MaxAttempts; i++ {
+		_, err = st.AuthClient.VerifyCode(ctx, &ssov1.VerifyCodeRequest{
+			Phone: phone,
+			Code:  "wrong",
+			AppId: appID,
+		})
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "invalid code")
+	}
+
+	_, err = st.AuthClient.VerifyCode(ctx, &ssov1.VerifyCodeRequest{
+		Phone: phone,
+		Code:  code,
+		AppId: appID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestVerifyCode_ConcurrentAttempts(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+
+	_, err := st.AuthClient.RequestCode(ctx, &ssov1.RequestCodeRequest{Phone: phone})
+	require.NoError(t, err)
+
+	code := sentCode(t, st, phone)
+
+	// Wrong codes sent at once are still counted one by one.
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		checked int
+	)
+	for i := 0; i < 3*st.Cfg.OTP.MaxAttempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := st.AuthClient.VerifyCode(ctx, &ssov1.VerifyCodeRequest{
+				Phone: phone,
+				Code:  "wrong",
+				AppId: appID,
+			})
+			if status.Code(err) == codes.InvalidArgument {
+				mu.Lock()
+				checked++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, checked, st.Cfg.OTP.MaxAttempts)
+
+	_, err = st.AuthClient.VerifyCode(ctx, &ssov1.VerifyCodeRequest{
+		Phone: phone,
+		Code:  code,
+		AppId: appID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestVerifyCode_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	tests := []struct {
+		name        string
+		phone       string
+		code        string
+		appID       int32
+		expectedErr string
+	}{
+		{
+			name:        "Verify with Empty Phone",
+			phone:       "",
+			code:        "123456",
+			appID:       appID,
+			expectedErr: "phone is required",
+		},
+		{
+			name:        "Verify with Empty Code",
+			phone:       randomFakePhone(),
+			code:        "",
+			appID:       appID,
+			expectedErr: "code is required",
+		},
+		{
+			name:        "Verify without Requested Code",
+			phone:       randomFakePhone(),
+			code:        "123456",
+			appID:       appID,
+			expectedErr: "invalid code",
+		},
+		{
+			name:        "Verify with Unknown App",
+			phone:       randomFakePhone(),
+			code:        "123456",
+			appID:       appID + 1000,
+			expectedErr: "invalid app_id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.VerifyCode(ctx, &ssov1.VerifyCodeRequest{
+				Phone: tt.phone,
+				Code:  tt.code,
+				AppId: tt.appID,
+			})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
+
+func TestRequestCode_ThrottledPerIP(t *testing.T) {
+	// Every test requests codes from the same IP, so the limit is tested on a dedicated server.
+	lockoutThreshold := 4
+	ctx, st := suite.NewServer(t, func(cfg *config.Config) {
+		cfg.LoginThrottle.CodeRequests = config.ThrottleConfig{
+			FreeAttempts:     2,
+			BaseDelay:        time.Second,
+			MaxDelay:         time.Second,
+			LockoutThreshold: &lockoutThreshold,
+			LockoutDuration:  30 * time.Minute,
+			ResetAfter:       time.Hour,
+		}
+	})
+
+	requestCode := func() error {
+		_, err := st.AuthClient.RequestCode(ctx, &ssov1.RequestCodeRequest{Phone: randomFakePhone()})
+		return err
+	}
+
+	// Every request counts, and the one beyond the free ones delays the next.
+	for range st.Cfg.LoginThrottle.CodeRequests.FreeAttempts + 1 {
+		require.NoError(t, requestCode())
+	}
+
+	delay := retryDelay(t, requestCode())
+	assert.Positive(t, delay)
+	assert.LessOrEqual(t, delay, st.Cfg.LoginThrottle.CodeRequests.MaxDelay)
+
+	// The request that reaches the threshold locks further ones out.
+	time.Sleep(delay + 100*time.Millisecond)
+	require.NoError(t, requestCode())
+
+	delay = retryDelay(t, requestCode())
+	assert.Greater(t, delay, st.Cfg.LoginThrottle.CodeRequests.MaxDelay)
+	assert.InDelta(t, st.Cfg.LoginThrottle.CodeRequests.LockoutDuration.Seconds(), delay.Seconds(), 5)
+}
+
+// sentCode returns the last code the file SMS sender has written for the phone.
+// The sender path in the config is relative to the repository root.
+func sentCode(t *testing.T, st *suite.Suite, phone string) string {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("..", st.Cfg.SMS.FilePath))
+	require.NoError(t, err)
+	defer f.Close()
+
+	var code string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 || fields[1] != phone {
+			continue
+		}
+
+		text := fields[2]
+		code = text[strings.LastIndex(text, " ")+1:]
+	}
+	require.NoError(t, scanner.Err())
+	require.NotEmpty(t, code, "no code sent to %s", phone)
+
+	return code
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	ssov1 "github.com/DenisPopkov/protos/gen/go/sso"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"sso/tests/suite"
+	"testing"
+)
+
+func TestGuest_RegisterAndUpgrade(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	deviceID := gofakeit.UUID()
+
+	respGuest, err := st.AuthClient.RegisterGuest(ctx, &ssov1.RegisterGuestRequest{
+		DeviceId: deviceID,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, respGuest.GetUserId())
+	require.NotEmpty(t, respGuest.GetToken())
+	require.NotEmpty(t, respGuest.GetRefreshToken())
+
+	resp := restRequest(t, st, http.MethodGet, "/sessions", respGuest.GetToken(), nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The device is bound to the same guest.
+	respAgain, err := st.AuthClient.RegisterGuest(ctx, &ssov1.RegisterGuestRequest{
+		DeviceId: deviceID,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, respGuest.GetUserId(), respAgain.GetUserId())
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	_, err = st.AuthClient.UpgradeGuest(ctx, &ssov1.UpgradeGuestRequest{
+		Token:    respGuest.GetToken(),
+		Phone:    phone,
+		Password: pass,
+	})
+	require.NoError(t, err)
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, respLogin.GetToken())
+
+	_, err = st.AuthClient.UpgradeGuest(ctx, &ssov1.UpgradeGuestRequest{
+		Token:    respLogin.GetToken(),
+		Phone:    randomFakePhone(),
+		Password: pass,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// Once upgraded, the device gets a new guest.
+	respNew, err := st.AuthClient.RegisterGuest(ctx, &ssov1.RegisterGuestRequest{
+		DeviceId: deviceID,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, respGuest.GetUserId(), respNew.GetUserId())
+}
+
+func TestGuest_UpgradeToTakenPhone(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	respGuest, err := st.AuthClient.RegisterGuest(ctx, &ssov1.RegisterGuestRequest{
+		DeviceId: gofakeit.UUID(),
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.UpgradeGuest(ctx, &ssov1.UpgradeGuestRequest{
+		Token:    respGuest.GetToken(),
+		Phone:    phone,
+		Password: pass,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+}
+
+func TestGuest_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, err := st.AuthClient.RegisterGuest(ctx, &ssov1.RegisterGuestRequest{AppId: appID})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AuthClient.RegisterGuest(ctx, &ssov1.RegisterGuestRequest{DeviceId: gofakeit.UUID()})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AuthClient.UpgradeGuest(ctx, &ssov1.UpgradeGuestRequest{
+		Token:    "invalid",
+		Phone:    randomFakePhone(),
+		Password: randomFakePassword(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
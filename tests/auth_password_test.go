@@ -0,0 +1,277 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	ssov1 "github.com/DenisPopkov/protos/gen/go/sso"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"path/filepath"
+	"sso/internal/storage/sqlite"
+	"sso/tests/suite"
+	"testing"
+	"time"
+)
+
+func TestChangePassword_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+	newPass := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ChangePassword(ctx, &ssov1.ChangePasswordRequest{
+		Token:       respLogin.GetToken(),
+		OldPassword: pass,
+		NewPassword: newPass,
+	})
+	require.NoError(t, err)
+
+	// Tokens issued before the change are revoked.
+	_, err = st.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: respLogin.GetRefreshToken(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid refresh token")
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid phone or password")
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: newPass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+}
+
+func TestChangePassword_LoginRightAfter(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+	newPass := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ChangePassword(ctx, &ssov1.ChangePasswordRequest{
+		Token:       respLogin.GetToken(),
+		OldPassword: pass,
+		NewPassword: newPass,
+	})
+	require.NoError(t, err)
+
+	// Most likely issued in the same second as the revocation, the new token must be accepted.
+	respNewLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: newPass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	resp := restRequest(t, st, http.MethodGet, "/user", respNewLogin.GetToken(), nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodGet, "/user", respLogin.GetToken(), nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestRevokeUserTokens_MillisecondPrecision(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    randomFakePhone(),
+		Password: randomFakePassword(),
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	storage, err := sqlite.New(filepath.Join("..", st.Cfg.StoragePath))
+	require.NoError(t, err)
+	defer storage.Stop()
+
+	// Most likely all in the same second: only the token issued before the revocation is revoked.
+	issuedBefore := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	require.NoError(t, storage.RevokeUserTokens(ctx, respReg.GetUserId(), time.Now().Add(time.Minute)))
+	time.Sleep(2 * time.Millisecond)
+	issuedAfter := time.Now()
+
+	revoked, err := storage.IsTokenRevoked(ctx, "before", respReg.GetUserId(), issuedBefore)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = storage.IsTokenRevoked(ctx, "after", respReg.GetUserId(), issuedAfter)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestChangePassword_RevocationOutlivesAppTokens(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	// Tokens of an app with its own TTL outlive the default ones, so the revocation must last as long.
+	const appTokenTTL = 48 * time.Hour
+	longAppID := createApp(t, st, appTokenTTL)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    longAppID,
+	})
+	require.NoError(t, err)
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    longAppID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ChangePassword(ctx, &ssov1.ChangePasswordRequest{
+		Token:       respLogin.GetToken(),
+		OldPassword: pass,
+		NewPassword: randomFakePassword(),
+	})
+	require.NoError(t, err)
+	changedAt := time.Now()
+
+	db, err := sql.Open("sqlite3", filepath.Join("..", st.Cfg.StoragePath))
+	require.NoError(t, err)
+	defer db.Close()
+
+	var expiresAt int64
+	err = db.QueryRowContext(ctx,
+		"SELECT expires_at FROM revoked_users WHERE user_id = ?", respReg.GetUserId(),
+	).Scan(&expiresAt)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, expiresAt, changedAt.Add(appTokenTTL).Unix()-1)
+}
+
+func TestChangePassword_WrongOldPassword(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ChangePassword(ctx, &ssov1.ChangePasswordRequest{
+		Token:       respLogin.GetToken(),
+		OldPassword: randomFakePassword(),
+		NewPassword: randomFakePassword(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid old password")
+}
+
+func TestResetPassword_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+	newPass := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.RequestPasswordReset(ctx, &ssov1.RequestPasswordResetRequest{Phone: phone})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ResetPassword(ctx, &ssov1.ResetPasswordRequest{
+		Phone:       phone,
+		Code:        sentCode(t, st, phone),
+		NewPassword: newPass,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: newPass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+}
+
+func TestResetPassword_InvalidCode(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, err := st.AuthClient.ResetPassword(ctx, &ssov1.ResetPasswordRequest{
+		Phone:       randomFakePhone(),
+		Code:        "123456",
+		NewPassword: randomFakePassword(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid code")
+}
+
+// createApp saves an app with its own access token TTL directly in the storage and returns its ID.
+func createApp(t *testing.T, st *suite.Suite, tokenTTL time.Duration) int32 {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join("..", st.Cfg.StoragePath))
+	require.NoError(t, err)
+	defer db.Close()
+
+	res, err := db.ExecContext(context.Background(),
+		"INSERT INTO apps(name, secret, token_ttl) VALUES(?, ?, ?)",
+		"test-"+gofakeit.LetterN(8), gofakeit.LetterN(32), int64(tokenTTL.Seconds()),
+	)
+	require.NoError(t, err)
+
+	id, err := res.LastInsertId()
+	require.NoError(t, err)
+
+	return int32(id)
+}
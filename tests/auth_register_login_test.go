@@ -1,30 +1,39 @@
 package tests
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	ssov1 "github.com/DenisPopkov/protos/gen/go/sso"
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http"
 	"sso/tests/suite"
 	"testing"
 	"time"
 )
 
 const (
-	appSecret      = "test-secret"
+	appID          = 1
 	passDefaultLen = 8
 )
 
 func TestRegisterLogin_Login_HappyPath(t *testing.T) {
 	ctx, st := suite.New(t)
 
-	phone := gofakeit.Phone()
+	phone := randomFakePhone()
 	pass := randomFakePassword()
 
 	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
 		Phone:    phone,
 		Password: pass,
+		AppId:    appID,
 	})
 
 	require.NoError(t, err)
@@ -33,6 +42,7 @@ func TestRegisterLogin_Login_HappyPath(t *testing.T) {
 	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
 		Phone:    phone,
 		Password: pass,
+		AppId:    appID,
 	})
 	require.NoError(t, err)
 
@@ -42,7 +52,7 @@ func TestRegisterLogin_Login_HappyPath(t *testing.T) {
 	loginTime := time.Now()
 
 	tokenParsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		return []byte(appSecret), nil
+		return publicKey(t, st, token.Header["kid"].(string)), nil
 	})
 	require.NoError(t, err)
 
@@ -51,6 +61,9 @@ func TestRegisterLogin_Login_HappyPath(t *testing.T) {
 
 	assert.Equal(t, respReg.GetUserId(), int64(claims["uid"].(float64)))
 	assert.Equal(t, phone, claims["phone"].(string))
+	assert.Equal(t, st.Cfg.TokenIssuer, claims["iss"].(string))
+	assert.Equal(t, appID, int(claims["app_id"].(float64)))
+	assert.NotEmpty(t, claims["jti"])
 
 	const deltaSeconds = 1
 
@@ -61,12 +74,13 @@ func TestRegisterLogin_Login_HappyPath(t *testing.T) {
 func TestRegisterLogin_DuplicatedRegistration(t *testing.T) {
 	ctx, st := suite.New(t)
 
-	phone := gofakeit.Phone()
+	phone := randomFakePhone()
 	pass := randomFakePassword()
 
 	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
 		Phone:    phone,
 		Password: pass,
+		AppId:    appID,
 	})
 	require.NoError(t, err)
 	require.NotEmpty(t, respReg.GetUserId())
@@ -74,12 +88,45 @@ func TestRegisterLogin_DuplicatedRegistration(t *testing.T) {
 	respReg, err = st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
 		Phone:    phone,
 		Password: pass,
+		AppId:    appID,
 	})
 	require.Error(t, err)
 	assert.Empty(t, respReg.GetUserId())
 	assert.ErrorContains(t, err, "user already exists")
 }
 
+func TestRegisterLogin_NormalizedPhone(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	// +79161234567 written as 8 (916) 123-45-67.
+	formatted := fmt.Sprintf("8 (%s) %s-%s-%s", phone[2:5], phone[5:8], phone[8:10], phone[10:])
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    formatted,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone[2:],
+		Password: pass,
+		AppId:    appID,
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "user already exists")
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+}
+
 func TestRegister_FailCases(t *testing.T) {
 	ctx, st := suite.New(t)
 
@@ -91,7 +138,7 @@ func TestRegister_FailCases(t *testing.T) {
 	}{
 		{
 			name:        "Register with Empty Password",
-			phone:       gofakeit.Phone(),
+			phone:       randomFakePhone(),
 			password:    "",
 			expectedErr: "password is required",
 		},
@@ -107,6 +154,24 @@ func TestRegister_FailCases(t *testing.T) {
 			password:    "",
 			expectedErr: "phone is required",
 		},
+		{
+			name:        "Register with Invalid Phone",
+			phone:       "12345",
+			password:    randomFakePassword(),
+			expectedErr: "invalid phone",
+		},
+		{
+			name:        "Register with Short Password",
+			phone:       randomFakePhone(),
+			password:    "abc",
+			expectedErr: "weak password",
+		},
+		{
+			name:        "Register with Common Password",
+			phone:       randomFakePhone(),
+			password:    "Password123",
+			expectedErr: "weak password: is too common",
+		},
 	}
 
 	for _, tt := range tests {
@@ -114,6 +179,7 @@ func TestRegister_FailCases(t *testing.T) {
 			_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
 				Phone:    tt.phone,
 				Password: tt.password,
+				AppId:    appID,
 			})
 			require.Error(t, err)
 			require.Contains(t, err.Error(), tt.expectedErr)
@@ -133,7 +199,7 @@ func TestLogin_FailCases(t *testing.T) {
 	}{
 		{
 			name:        "Login with Empty Password",
-			phone:       gofakeit.Phone(),
+			phone:       randomFakePhone(),
 			password:    "",
 			expectedErr: "password is required",
 		},
@@ -151,7 +217,7 @@ func TestLogin_FailCases(t *testing.T) {
 		},
 		{
 			name:        "Login with Non-Matching Password",
-			phone:       gofakeit.Phone(),
+			phone:       randomFakePhone(),
 			password:    randomFakePassword(),
 			expectedErr: "invalid phone or password",
 		},
@@ -160,14 +226,16 @@ func TestLogin_FailCases(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
-				Phone:    gofakeit.Phone(),
+				Phone:    randomFakePhone(),
 				Password: randomFakePassword(),
+				AppId:    appID,
 			})
 			require.NoError(t, err)
 
 			_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
 				Phone:    tt.phone,
 				Password: tt.password,
+				AppId:    appID,
 			})
 			require.Error(t, err)
 			require.Contains(t, err.Error(), tt.expectedErr)
@@ -175,6 +243,56 @@ func TestLogin_FailCases(t *testing.T) {
 	}
 }
 
+// randomFakePhone returns a random Russian mobile number in E.164 format, as the service stores it.
+func randomFakePhone() string {
+	return "+79" + gofakeit.Numerify("#########")
+}
+
 func randomFakePassword() string {
 	return gofakeit.Password(true, true, true, true, false, passDefaultLen)
 }
+
+// publicKey fetches the JWKS published by the REST server and returns the key with the given ID.
+func publicKey(t *testing.T, st *suite.Suite, kid string) crypto.PublicKey {
+	t.Helper()
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/.well-known/jwks.json", st.Cfg.REST.Port))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			X   string `json:"x"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&jwks))
+
+	for _, key := range jwks.Keys {
+		if key.Kid != kid {
+			continue
+		}
+
+		switch key.Kty {
+		case "OKP":
+			x, err := base64.RawURLEncoding.DecodeString(key.X)
+			require.NoError(t, err)
+
+			return ed25519.PublicKey(x)
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(key.N)
+			require.NoError(t, err)
+			e, err := base64.RawURLEncoding.DecodeString(key.E)
+			require.NoError(t, err)
+
+			return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		}
+	}
+
+	t.Fatalf("key %q is not published", kid)
+
+	return nil
+}
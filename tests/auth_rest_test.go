@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sso/tests/suite"
+	"strconv"
+	"testing"
+)
+
+type restCredentials struct {
+	Phone    string `json:"phone"`
+	Password string `json:"password"`
+	AppID    int64  `json:"appId"`
+}
+
+type restError struct {
+	Error string `json:"error"`
+}
+
+func TestREST_RegisterLogin(t *testing.T) {
+	_, st := suite.New(t)
+
+	creds := restCredentials{
+		Phone:    randomFakePhone(),
+		Password: randomFakePassword(),
+		AppID:    appID,
+	}
+
+	resp := restRequest(t, st, http.MethodPost, "/auth/register", "", creds)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var registered struct {
+		UserID int64 `json:"userId"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&registered))
+	assert.NotEmpty(t, registered.UserID)
+
+	resp = restRequest(t, st, http.MethodPost, "/auth/register", "", creds)
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	var errResp restError
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, "user already exists", errResp.Error)
+
+	resp = restRequest(t, st, http.MethodPost, "/auth/login", "", creds)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var tokens struct {
+		AccessToken  string `json:"accessToken"`
+		RefreshToken string `json:"refreshToken"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tokens))
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	resp = restRequest(t, st, http.MethodGet, "/sessions", tokens.AccessToken, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestREST_LoginFailCases(t *testing.T) {
+	_, st := suite.New(t)
+
+	creds := restCredentials{
+		Phone:    randomFakePhone(),
+		Password: randomFakePassword(),
+		AppID:    appID,
+	}
+
+	resp := restRequest(t, st, http.MethodPost, "/auth/register", "", creds)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodPost, "/auth/login", "", restCredentials{Phone: creds.Phone, AppID: appID})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	wrong := creds
+	wrong.Password = randomFakePassword()
+
+	// Free attempts fail as usual, then logins are delayed.
+	for i := 0; i < st.Cfg.LoginThrottle.Phone.FreeAttempts; i++ {
+		resp = restRequest(t, st, http.MethodPost, "/auth/login", "", wrong)
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		var errResp restError
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		assert.Equal(t, "invalid phone or password", errResp.Error)
+	}
+
+	resp = restRequest(t, st, http.MethodPost, "/auth/login", "", wrong)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodPost, "/auth/login", "", creds)
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	require.NoError(t, err)
+	assert.Positive(t, retryAfter)
+}
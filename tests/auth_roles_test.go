@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	ssov1 "github.com/DenisPopkov/protos/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"path/filepath"
+	"sso/internal/domain/models"
+	"sso/internal/storage/sqlite"
+	"sso/tests/suite"
+	"testing"
+)
+
+func TestRoles_NewUser(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	serviceCtx := withCredential(ctx, createAPIKey(t, st, models.PermissionReadUsers))
+
+	respRoles, err := st.AuthClient.GetRoles(serviceCtx, &ssov1.GetRolesRequest{UserId: respReg.GetUserId()})
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.RoleUser}, respRoles.GetRoles())
+
+	respIsAdmin, err := st.AuthClient.IsAdmin(serviceCtx, &ssov1.IsAdminRequest{UserId: respReg.GetUserId()})
+	require.NoError(t, err)
+	assert.False(t, respIsAdmin.GetIsAdmin())
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	path := fmt.Sprintf("/admin/users/%d/roles", respReg.GetUserId())
+	resp := restRequest(t, st, http.MethodPut, path, respLogin.GetToken(), map[string][]string{
+		"roles": {models.RoleAdmin},
+	})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestRoles_AdminSetsRoles(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	adminPhone := randomFakePhone()
+	adminPass := randomFakePassword()
+
+	respAdmin, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    adminPhone,
+		Password: adminPass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	grantRoles(t, st, respAdmin.GetUserId(), models.RoleAdmin, models.RoleUser)
+
+	serviceCtx := withCredential(ctx, createAPIKey(t, st, models.PermissionReadUsers))
+
+	respIsAdmin, err := st.AuthClient.IsAdmin(serviceCtx, &ssov1.IsAdminRequest{UserId: respAdmin.GetUserId()})
+	require.NoError(t, err)
+	assert.True(t, respIsAdmin.GetIsAdmin())
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    adminPhone,
+		Password: adminPass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	respUser, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    randomFakePhone(),
+		Password: randomFakePassword(),
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	path := fmt.Sprintf("/admin/users/%d/roles", respUser.GetUserId())
+
+	resp := restRequest(t, st, http.MethodPut, path, respLogin.GetToken(), map[string][]string{
+		"roles": {"superuser"},
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodPut, path, respLogin.GetToken(), map[string][]string{
+		"roles": {models.RoleEditor},
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	respRoles, err := st.AuthClient.GetRoles(serviceCtx, &ssov1.GetRolesRequest{UserId: respUser.GetUserId()})
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.RoleEditor, models.RoleUser}, respRoles.GetRoles())
+
+	resp = restRequest(t, st, http.MethodPut, "/admin/users/0/roles", respLogin.GetToken(), map[string][]string{
+		"roles": {models.RoleEditor},
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestRoles_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	serviceCtx := withCredential(ctx, createAPIKey(t, st, models.PermissionReadUsers))
+
+	_, err := st.AuthClient.GetRoles(serviceCtx, &ssov1.GetRolesRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AuthClient.IsAdmin(serviceCtx, &ssov1.IsAdminRequest{UserId: 1 << 62})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestRoles_RequireCredential(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    randomFakePhone(),
+		Password: randomFakePassword(),
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.IsAdmin(ctx, &ssov1.IsAdminRequest{UserId: respReg.GetUserId()})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = st.AuthClient.GetRoles(ctx, &ssov1.GetRolesRequest{UserId: respReg.GetUserId()})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	keyCtx := withCredential(ctx, createAPIKey(t, st, models.PermissionValidateTokens))
+
+	_, err = st.AuthClient.GetRoles(keyCtx, &ssov1.GetRolesRequest{UserId: respReg.GetUserId()})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// grantRoles sets roles of the user directly in the storage, as cmd/set-roles does to appoint the first admin.
+func grantRoles(t *testing.T, st *suite.Suite, userID int64, roles ...string) {
+	t.Helper()
+
+	storage, err := sqlite.New(filepath.Join("..", st.Cfg.StoragePath))
+	require.NoError(t, err)
+	defer storage.Stop()
+
+	require.NoError(t, storage.SetUserRoles(context.Background(), userID, roles))
+}
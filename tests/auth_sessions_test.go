@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"encoding/json"
+	ssov1 "github.com/DenisPopkov/protos/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"sso/tests/suite"
+	"testing"
+)
+
+func TestSessions_ListAndRevoke(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	phoneCtx := metadata.AppendToOutgoingContext(ctx, "x-device-name", "Pixel 8", "x-platform", "android")
+	onPhone, err := st.AuthClient.Login(phoneCtx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	tabletCtx := metadata.AppendToOutgoingContext(ctx, "x-device-name", "iPad", "x-platform", "ios")
+	onTablet, err := st.AuthClient.Login(tabletCtx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	var sessions []struct {
+		ID         string `json:"id"`
+		DeviceName string `json:"deviceName"`
+		Platform   string `json:"platform"`
+		Current    bool   `json:"current"`
+	}
+	resp := restRequest(t, st, http.MethodGet, "/sessions", onPhone.GetToken(), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&sessions))
+	require.Len(t, sessions, 2)
+
+	var tabletSessionID string
+	for _, session := range sessions {
+		switch session.DeviceName {
+		case "Pixel 8":
+			assert.Equal(t, "android", session.Platform)
+			assert.True(t, session.Current)
+		case "iPad":
+			assert.Equal(t, "ios", session.Platform)
+			assert.False(t, session.Current)
+			tabletSessionID = session.ID
+		default:
+			t.Fatalf("unexpected session %q", session.DeviceName)
+		}
+	}
+
+	resp = restRequest(t, st, http.MethodDelete, "/sessions/"+tabletSessionID, onPhone.GetToken(), nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// Both tokens of the revoked session stop working at once.
+	resp = restRequest(t, st, http.MethodGet, "/sessions", onTablet.GetToken(), nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	_, err = st.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{RefreshToken: onTablet.GetRefreshToken()})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// The other session is not affected.
+	resp = restRequest(t, st, http.MethodGet, "/sessions", onPhone.GetToken(), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodDelete, "/sessions/"+tabletSessionID, onPhone.GetToken(), nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	ssov1 "github.com/DenisPopkov/protos/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/internal/config"
+	"sso/tests/suite"
+	"testing"
+	"time"
+)
+
+func TestLogin_BlockedAfterFailures(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	// Free attempts plus the first one that is delayed.
+	for i := 0; i <= st.Cfg.LoginThrottle.Phone.FreeAttempts; i++ {
+		_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+			Phone:    phone,
+			Password: randomFakePassword(),
+			AppId:    appID,
+		})
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "invalid phone or password")
+	}
+
+	// Even the right password is refused until the delay is over.
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	assert.Positive(t, retryDelay(t, err))
+}
+
+func TestLogin_BlockedPerIP(t *testing.T) {
+	// Every test logs in from the same IP, so the limits per IP are tested on a dedicated server.
+	lockoutThreshold := 4
+	ctx, st := suite.NewServer(t, func(cfg *config.Config) {
+		cfg.LoginThrottle.IP = config.ThrottleConfig{
+			FreeAttempts:     2,
+			BaseDelay:        time.Second,
+			MaxDelay:         time.Second,
+			LockoutThreshold: &lockoutThreshold,
+			LockoutDuration:  30 * time.Minute,
+			ResetAfter:       time.Hour,
+		}
+	})
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	// Failures with other phones count for the IP only. The last one is delayed.
+	failLogin := func() {
+		_, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+			Phone:    randomFakePhone(),
+			Password: randomFakePassword(),
+			AppId:    appID,
+		})
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "invalid phone or password")
+	}
+	for range st.Cfg.LoginThrottle.IP.FreeAttempts + 1 {
+		failLogin()
+	}
+
+	// The backoff delays even the right password of another phone.
+	login := func() error {
+		_, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+			Phone:    phone,
+			Password: pass,
+			AppId:    appID,
+		})
+		return err
+	}
+
+	delay := retryDelay(t, login())
+	assert.Positive(t, delay)
+	assert.LessOrEqual(t, delay, st.Cfg.LoginThrottle.IP.MaxDelay)
+
+	time.Sleep(delay + 100*time.Millisecond)
+	failLogin()
+
+	// The failure that reaches the threshold locks logins from the IP out.
+	delay = retryDelay(t, login())
+	assert.Greater(t, delay, st.Cfg.LoginThrottle.IP.MaxDelay)
+	assert.InDelta(t, st.Cfg.LoginThrottle.IP.LockoutDuration.Seconds(), delay.Seconds(), 5)
+}
+
+// retryDelay returns the delay in the RetryInfo of the ResourceExhausted error.
+func retryDelay(t *testing.T, err error) time.Duration {
+	t.Helper()
+
+	require.Error(t, err)
+
+	s := status.Convert(err)
+	require.Equal(t, codes.ResourceExhausted, s.Code())
+
+	var retryInfo *errdetails.RetryInfo
+	for _, d := range s.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			retryInfo = ri
+		}
+	}
+	require.NotNil(t, retryInfo)
+
+	return retryInfo.GetRetryDelay().AsDuration()
+}
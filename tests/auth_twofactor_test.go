@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	ssov1 "github.com/DenisPopkov/protos/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"sso/internal/domain/models"
+	"sso/internal/lib/totp"
+	"sso/tests/suite"
+	"testing"
+	"time"
+)
+
+func TestTwoFactor_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, respLogin.GetToken())
+	assert.Empty(t, respLogin.GetTwoFactorChallenge())
+
+	var enrollment struct {
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+	}
+	resp := restRequest(t, st, http.MethodPost, "/2fa/totp", respLogin.GetToken(), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&enrollment))
+	require.NotEmpty(t, enrollment.Secret)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/")
+
+	code, err := totp.Code(enrollment.Secret, time.Now())
+	require.NoError(t, err)
+
+	var confirmed struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}
+	resp = restRequest(t, st, http.MethodPost, "/2fa/totp/confirm", respLogin.GetToken(), map[string]string{"code": code})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&confirmed))
+	require.Len(t, confirmed.RecoveryCodes, st.Cfg.TwoFactor.RecoveryCodes)
+
+	// The password alone is no longer enough.
+	respLogin, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, respLogin.GetToken())
+	require.NotEmpty(t, respLogin.GetTwoFactorChallenge())
+
+	// The code used for confirmation can't be used again.
+	_, err = st.AuthClient.VerifyTwoFactor(ctx, &ssov1.VerifyTwoFactorRequest{
+		Challenge: respLogin.GetTwoFactorChallenge(),
+		Code:      code,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	respVerify, err := st.AuthClient.VerifyTwoFactor(ctx, &ssov1.VerifyTwoFactorRequest{
+		Challenge: respLogin.GetTwoFactorChallenge(),
+		Code:      confirmed.RecoveryCodes[0],
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, respVerify.GetToken())
+	assert.NotEmpty(t, respVerify.GetRefreshToken())
+
+	// A challenge is accepted only once.
+	_, err = st.AuthClient.VerifyTwoFactor(ctx, &ssov1.VerifyTwoFactorRequest{
+		Challenge: respLogin.GetTwoFactorChallenge(),
+		Code:      confirmed.RecoveryCodes[1],
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestTwoFactor_WrongCodesThrottled(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	var enrollment struct {
+		Secret string `json:"secret"`
+	}
+	resp := restRequest(t, st, http.MethodPost, "/2fa/totp", respLogin.GetToken(), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&enrollment))
+
+	code, err := totp.Code(enrollment.Secret, time.Now())
+	require.NoError(t, err)
+
+	resp = restRequest(t, st, http.MethodPost, "/2fa/totp/confirm", respLogin.GetToken(), map[string]string{"code": code})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The right password doesn't reset failed attempts, so wrong codes add up across challenges.
+	for i := 0; i <= st.Cfg.LoginThrottle.Phone.FreeAttempts; i++ {
+		respLogin, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+			Phone:    phone,
+			Password: pass,
+			AppId:    appID,
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, respLogin.GetTwoFactorChallenge())
+
+		_, err = st.AuthClient.VerifyTwoFactor(ctx, &ssov1.VerifyTwoFactorRequest{
+			Challenge: respLogin.GetTwoFactorChallenge(),
+			Code:      "wrong-code",
+		})
+		require.Error(t, err)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestTwoFactor_RequiredButNotEnabled(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	adminToken := registerWithRoles(t, st, models.RoleAdmin)
+
+	path := fmt.Sprintf("/admin/users/%d/two-factor", respReg.GetUserId())
+	resp := restRequest(t, st, http.MethodPut, path, adminToken, map[string]bool{"required": true})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// The password alone must not let the user in to enroll.
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+// restRequest sends an authorized JSON request to the REST server.
+func restRequest(t *testing.T, st *suite.Suite, method string, path string, token string, body any) *http.Response {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("http://localhost:%d%s", st.Cfg.REST.Port, path), &buf)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
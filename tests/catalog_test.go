@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sso/internal/domain/models"
+	"sso/tests/suite"
+	"testing"
+)
+
+func TestCatalog_PagesThroughFavorites(t *testing.T) {
+	_, st := suite.New(t)
+
+	editorToken := registerWithRoles(t, st, models.RoleEditor)
+	first, second := createAuthor(t, st, editorToken), createAuthor(t, st, editorToken)
+	createAuthor(t, st, editorToken)
+	token := registerWithRoles(t, st)
+
+	for _, author := range []models.Author{first, second} {
+		resp := restRequest(t, st, http.MethodPut, fmt.Sprintf("/favorites/author/%d", author.ID), token, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// The authors have the same name, so they are sorted by ID within it.
+	page := listAuthorsPage(t, st, token, "/authors?isFave=true&sort=name&limit=1")
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, first.ID, page.Items[0].ID)
+	assert.True(t, page.Items[0].IsFave)
+	require.NotEmpty(t, page.NextCursor)
+
+	page = listAuthorsPage(t, st, token, "/authors?isFave=true&sort=name&limit=1&cursor="+page.NextCursor)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, second.ID, page.Items[0].ID)
+
+	// The cursor holds a position in the order by name, ascending, and is refused in another order.
+	for _, path := range []string{
+		"/authors?isFave=true&limit=1&cursor=",
+		"/authors?isFave=true&sort=name&order=desc&limit=1&cursor=",
+	} {
+		resp := restRequest(t, st, http.MethodGet, path+page.NextCursor, token, nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, path)
+	}
+
+	page = listAuthorsPage(t, st, token, "/authors?isFave=true&sort=name&limit=1&cursor="+page.NextCursor)
+	assert.Empty(t, page.Items)
+	assert.Empty(t, page.NextCursor)
+
+	page = listAuthorsPage(t, st, token, "/authors?isFave=true&order=desc&limit=10")
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, second.ID, page.Items[0].ID)
+	assert.Empty(t, page.NextCursor)
+
+	resp := restRequest(t, st, http.MethodGet, "/authors?sort=clip", token, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestCatalog_UnpaginatedArray(t *testing.T) {
+	_, st := suite.New(t)
+
+	editorToken := registerWithRoles(t, st, models.RoleEditor)
+	author := createAuthor(t, st, editorToken)
+	token := registerWithRoles(t, st)
+
+	resp := restRequest(t, st, http.MethodPut, fmt.Sprintf("/favorites/author/%d", author.ID), token, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Without limit and cursor the whole catalog comes as a bare array, as it did before pagination.
+	resp = restRequest(t, st, http.MethodGet, "/authors?isFave=true", token, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var authors []models.Author
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&authors))
+	require.Len(t, authors, 1)
+	assert.Equal(t, author.ID, authors[0].ID)
+}
+
+type authorsPage struct {
+	Items      []models.Author `json:"items"`
+	NextCursor string          `json:"next_cursor"`
+}
+
+// listAuthorsPage requests a page of the catalog of authors as the user sees it.
+func listAuthorsPage(t *testing.T, st *suite.Suite, token string, path string) authorsPage {
+	t.Helper()
+
+	resp := restRequest(t, st, http.MethodGet, path, token, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var page authorsPage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+
+	return page
+}
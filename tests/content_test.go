@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sso/internal/domain/models"
+	"sso/tests/suite"
+	"testing"
+)
+
+func TestContent_EditorManagesCatalog(t *testing.T) {
+	_, st := suite.New(t)
+
+	editorToken := registerWithRoles(t, st, models.RoleEditor)
+	userToken := registerWithRoles(t, st)
+
+	resp := restRequest(t, st, http.MethodPost, "/admin/clips", userToken, map[string]any{})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodPost, "/admin/clips", editorToken, models.Clip{Image: "https://iili.io/clip.png"})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodPost, "/admin/clips", editorToken, models.Clip{
+		Image: "https://iili.io/clip.png",
+		Text:  []models.ClipText{{Title: "Детство", Text: "Родился в Москве."}},
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var clip models.Clip
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&clip))
+	require.NotZero(t, clip.ID)
+	assert.Equal(t, "Родился в Москве.", clip.Text[0].Text)
+
+	quiz := models.Quiz{
+		ID:       clip.ID,
+		Question: "Где родился писатель?",
+		Answers:  []models.Answer{{Text: "В Москве", IsRight: true}, {Text: "В Туле"}},
+	}
+	resp = restRequest(t, st, http.MethodPost, "/admin/quizzes", editorToken, quiz)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodPost, "/admin/quizzes", editorToken, quiz)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodPost, "/admin/authors", editorToken, models.Author{Name: "Иван Тургенев", Clip: clip.ID + 1000})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodPost, "/admin/authors", editorToken, models.Author{
+		Name:  "Иван Тургенев",
+		Image: "https://iili.io/turgenev.png",
+		Clip:  clip.ID,
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var author models.Author
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&author))
+	assert.Equal(t, "Иван Тургенев", author.Name)
+
+	// Both existing and new users see the new author in the catalog.
+	assert.Contains(t, listAuthors(t, st, userToken), author)
+	assert.Contains(t, listAuthors(t, st, registerWithRoles(t, st)), author)
+
+	author.Name = "Иван Сергеевич Тургенев"
+	resp = restRequest(t, st, http.MethodPut, fmt.Sprintf("/admin/authors/%d", author.ID), editorToken, author)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var updated models.Author
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&updated))
+	assert.Equal(t, author, updated)
+
+	resp = restRequest(t, st, http.MethodDelete, fmt.Sprintf("/admin/clips/%d", clip.ID), editorToken, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodDelete, fmt.Sprintf("/admin/authors/%d", author.ID), editorToken, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodDelete, fmt.Sprintf("/admin/authors/%d", author.ID), editorToken, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodDelete, fmt.Sprintf("/admin/clips/%d", clip.ID), editorToken, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+// registerWithRoles registers a user with the roles in addition to the default one and returns their access token.
+func registerWithRoles(t *testing.T, st *suite.Suite, roles ...string) string {
+	t.Helper()
+
+	creds := restCredentials{
+		Phone:    randomFakePhone(),
+		Password: randomFakePassword(),
+		AppID:    appID,
+	}
+
+	resp := restRequest(t, st, http.MethodPost, "/auth/register", "", creds)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var registered struct {
+		UserID int64 `json:"userId"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&registered))
+
+	if len(roles) > 0 {
+		grantRoles(t, st, registered.UserID, append(roles, models.RoleUser)...)
+	}
+
+	return restLogin(t, st, creds)
+}
@@ -0,0 +1,259 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sso/internal/domain/models"
+	"sso/tests/suite"
+	"testing"
+	"time"
+)
+
+func TestFavorites_SetIsIdempotent(t *testing.T) {
+	_, st := suite.New(t)
+
+	author := createAuthor(t, st, registerWithRoles(t, st, models.RoleEditor))
+	token := registerWithRoles(t, st)
+	path := fmt.Sprintf("/favorites/author/%d", author.ID)
+
+	// Repeating a request, as a retry or a double tap does, keeps the state.
+	for range 2 {
+		resp := restRequest(t, st, http.MethodPut, path, token, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var favorite models.Favorite
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&favorite))
+		assert.Equal(t, models.Favorite{Type: models.FavoriteTypeAuthor, ID: author.ID, IsFave: true}, favorite)
+	}
+
+	author.IsFave = true
+	assert.Contains(t, listAuthors(t, st, token), author)
+
+	for range 2 {
+		resp := restRequest(t, st, http.MethodDelete, path, token, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var favorite models.Favorite
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&favorite))
+		assert.False(t, favorite.IsFave)
+	}
+
+	author.IsFave = false
+	assert.Contains(t, listAuthors(t, st, token), author)
+
+	resp := restRequest(t, st, http.MethodPut, fmt.Sprintf("/favorites/author/%d", author.ID+1000), token, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodPut, fmt.Sprintf("/favorites/clip/%d", author.Clip), token, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestFavorites_List(t *testing.T) {
+	_, st := suite.New(t)
+
+	editorToken := registerWithRoles(t, st, models.RoleEditor)
+	first, second := createAuthor(t, st, editorToken), createAuthor(t, st, editorToken)
+	token := registerWithRoles(t, st)
+
+	for _, author := range []models.Author{first, second} {
+		resp := restRequest(t, st, http.MethodPut, fmt.Sprintf("/favorites/author/%d", author.ID), token, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	page := listFavorites(t, st, token, "/favorites?limit=1")
+	require.Len(t, page.Favorites, 1)
+	assert.Equal(t, second.ID, page.Favorites[0].ID)
+	assert.Equal(t, models.FavoriteTypeAuthor, page.Favorites[0].Type)
+	assert.Equal(t, second.Name, page.Favorites[0].Name)
+	require.NotNil(t, page.Favorites[0].FavoritedAt)
+	require.NotEmpty(t, page.NextCursor)
+
+	page = listFavorites(t, st, token, "/favorites?limit=1&cursor="+page.NextCursor)
+	require.Len(t, page.Favorites, 1)
+	assert.Equal(t, first.ID, page.Favorites[0].ID)
+
+	page = listFavorites(t, st, token, "/favorites?limit=1&cursor="+page.NextCursor)
+	assert.Empty(t, page.Favorites)
+	assert.Empty(t, page.NextCursor)
+
+	page = listFavorites(t, st, token, "/favorites?order=asc")
+	require.Len(t, page.Favorites, 2)
+	assert.Equal(t, first.ID, page.Favorites[0].ID)
+	assert.Empty(t, page.NextCursor)
+
+	page = listFavorites(t, st, token, "/favorites?type=poet")
+	assert.Empty(t, page.Favorites)
+
+	resp := restRequest(t, st, http.MethodGet, "/favorites?cursor=garbage", token, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestFavorites_ListWithinOneSecond(t *testing.T) {
+	_, st := suite.New(t)
+
+	editorToken := registerWithRoles(t, st, models.RoleEditor)
+	firstAuthor, secondAuthor := createAuthor(t, st, editorToken), createAuthor(t, st, editorToken)
+	token := registerWithRoles(t, st)
+
+	// Favorites added in the same second are sorted by the order of models.FavoriteTypes and then by ID.
+	// An article goes last, though its type name comes first alphabetically.
+	want := []models.Favorite{
+		{Type: models.FavoriteTypeAuthor, ID: firstAuthor.ID},
+		{Type: models.FavoriteTypeAuthor, ID: secondAuthor.ID},
+		{Type: models.FavoriteTypePoet, ID: createContent(t, st, editorToken, "/admin/poets", models.Poet{
+			Name:  "Александр Блок",
+			Image: "https://iili.io/blok.png",
+			Clip:  firstAuthor.Clip,
+		})},
+		{Type: models.FavoriteTypeArticle, ID: createContent(t, st, editorToken, "/admin/articles", models.Article{
+			Name:        "Серебряный век",
+			Description: "Русская поэзия начала XX века.",
+			Image:       "https://iili.io/age.png",
+			Clip:        firstAuthor.Clip,
+		})},
+		{Type: models.FavoriteTypeArticle, ID: createContent(t, st, editorToken, "/admin/articles", models.Article{
+			Name:        "Золотой век",
+			Description: "Русская литература XIX века.",
+			Image:       "https://iili.io/age.png",
+			Clip:        firstAuthor.Clip,
+		})},
+	}
+
+	for _, favorite := range want {
+		resp := restRequest(t, st, http.MethodPut, fmt.Sprintf("/favorites/%s/%d", favorite.Type, favorite.ID), token, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	setFavoritedAt(t, st, time.Now().Add(-time.Hour), want)
+
+	for _, order := range []string{"asc", "desc"} {
+		expected := slices.Clone(want)
+		if order == "desc" {
+			slices.Reverse(expected)
+		}
+
+		var (
+			got  []models.Favorite
+			next string
+		)
+		for range len(want) + 1 {
+			page := listFavorites(t, st, token, "/favorites?limit=1&order="+order+"&cursor="+next)
+			for _, favorite := range page.Favorites {
+				got = append(got, models.Favorite{Type: favorite.Type, ID: favorite.ID})
+			}
+
+			next = page.NextCursor
+			if next == "" {
+				break
+			}
+		}
+		assert.Equal(t, expected, got, order)
+	}
+
+	// A cursor of a page in one order is not valid in the other.
+	page := listFavorites(t, st, token, "/favorites?limit=1&order=asc")
+	require.NotEmpty(t, page.NextCursor)
+
+	resp := restRequest(t, st, http.MethodGet, "/favorites?limit=1&order=desc&cursor="+page.NextCursor, token, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+type favoritesPage struct {
+	Favorites  []models.Favorite `json:"favorites"`
+	NextCursor string            `json:"nextCursor"`
+}
+
+// listFavorites requests a page of favorites of the user.
+func listFavorites(t *testing.T, st *suite.Suite, token string, path string) favoritesPage {
+	t.Helper()
+
+	resp := restRequest(t, st, http.MethodGet, path, token, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var page favoritesPage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+
+	return page
+}
+
+// createAuthor creates an author with a clip on behalf of the editor.
+func createAuthor(t *testing.T, st *suite.Suite, editorToken string) models.Author {
+	t.Helper()
+
+	resp := restRequest(t, st, http.MethodPost, "/admin/clips", editorToken, models.Clip{
+		Image: "https://iili.io/clip.png",
+		Text:  []models.ClipText{{Title: "Детство", Text: "Родился в Орле."}},
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var clip models.Clip
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&clip))
+
+	resp = restRequest(t, st, http.MethodPost, "/admin/authors", editorToken, models.Author{
+		Name:  "Иван Тургенев",
+		Image: "https://iili.io/turgenev.png",
+		Clip:  clip.ID,
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var author models.Author
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&author))
+
+	return author
+}
+
+// createContent creates a content item on behalf of the editor and returns its ID.
+func createContent(t *testing.T, st *suite.Suite, editorToken string, path string, item any) int64 {
+	t.Helper()
+
+	resp := restRequest(t, st, http.MethodPost, path, editorToken, item)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+	return created.ID
+}
+
+// setFavoritedAt moves the favorites to the same time directly in the storage,
+// as if they were all added in one second.
+func setFavoritedAt(t *testing.T, st *suite.Suite, favoritedAt time.Time, favorites []models.Favorite) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join("..", st.Cfg.StoragePath))
+	require.NoError(t, err)
+	defer db.Close()
+
+	tables := map[string]string{
+		models.FavoriteTypeAuthor:  "UPDATE authors SET favorited_at = ? WHERE authorId = ?",
+		models.FavoriteTypePoet:    "UPDATE poets SET favorited_at = ? WHERE poetId = ?",
+		models.FavoriteTypeArticle: "UPDATE articles SET favorited_at = ? WHERE articleId = ?",
+	}
+	for _, favorite := range favorites {
+		_, err := db.ExecContext(context.Background(), tables[favorite.Type], favoritedAt.Unix(), favorite.ID)
+		require.NoError(t, err)
+	}
+}
+
+// listAuthors returns the catalog of authors as the user sees it.
+func listAuthors(t *testing.T, st *suite.Suite, token string) []models.Author {
+	t.Helper()
+
+	resp := restRequest(t, st, http.MethodGet, "/authors", token, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var authors []models.Author
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&authors))
+
+	return authors
+}
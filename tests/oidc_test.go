@@ -0,0 +1,252 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	ssov1 "github.com/DenisPopkov/protos/gen/go/sso"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sso/internal/domain/models"
+	"sso/internal/storage/sqlite"
+	"sso/tests/suite"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const redirectURI = "http://localhost/callback"
+
+func TestOIDC_AuthorizationCodeFlow(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	var discovery struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	resp := oidcRequest(t, st, http.MethodGet, "/.well-known/openid-configuration", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&discovery))
+	assert.Equal(t, st.Cfg.OIDC.Issuer, discovery.Issuer)
+	assert.Equal(t, st.Cfg.OIDC.Issuer+"/oauth/token", discovery.TokenEndpoint)
+
+	clientID := registerClient(t, st)
+	verifier := gofakeit.LetterN(64)
+	nonce := gofakeit.UUID()
+
+	params := authorizeParams(clientID, verifier, nonce)
+
+	resp = oidcRequest(t, st, http.MethodGet, "/oauth/authorize?"+params.Encode(), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/html")
+
+	params.Set("phone", phone)
+	params.Set("password", randomFakePassword())
+	resp = oidcRequest(t, st, http.MethodPost, "/oauth/authorize", params)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	params.Set("password", pass)
+	resp = oidcRequest(t, st, http.MethodPost, "/oauth/authorize", params)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "state-1", location.Query().Get("state"))
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	exchange := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {clientID},
+		"code_verifier": {verifier},
+	}
+
+	var tokens struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int64  `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		IDToken      string `json:"id_token"`
+	}
+	resp = oidcRequest(t, st, http.MethodPost, "/oauth/token", exchange)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tokens))
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, int64(st.Cfg.TokenTTL.Seconds()), tokens.ExpiresIn)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	idToken, err := jwt.Parse(tokens.IDToken, func(token *jwt.Token) (interface{}, error) {
+		return publicKey(t, st, token.Header["kid"].(string)), nil
+	})
+	require.NoError(t, err)
+
+	claims := idToken.Claims.(jwt.MapClaims)
+	assert.Equal(t, st.Cfg.OIDC.Issuer, claims["iss"])
+	assert.Equal(t, clientID, claims["aud"].([]interface{})[0])
+	assert.Equal(t, strconv.FormatInt(respReg.GetUserId(), 10), claims["sub"])
+	assert.Equal(t, nonce, claims["nonce"])
+	assert.Equal(t, phone, claims["phone_number"])
+
+	// A code is exchanged only once.
+	resp = oidcRequest(t, st, http.MethodPost, "/oauth/token", exchange)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var info struct {
+		Subject     string `json:"sub"`
+		PhoneNumber string `json:"phone_number"`
+	}
+	resp = restRequest(t, st, http.MethodGet, "/oauth/userinfo", tokens.AccessToken, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	assert.Equal(t, strconv.FormatInt(respReg.GetUserId(), 10), info.Subject)
+	assert.Equal(t, phone, info.PhoneNumber)
+
+	resp = oidcRequest(t, st, http.MethodPost, "/oauth/token", url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tokens.RefreshToken},
+		"client_id":     {clientID},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestOIDC_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	phone := randomFakePhone()
+	pass := randomFakePassword()
+
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Phone:    phone,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	clientID := registerClient(t, st)
+	verifier := gofakeit.LetterN(64)
+
+	// Unknown redirect URIs are not redirected to.
+	params := authorizeParams(clientID, verifier, "")
+	params.Set("redirect_uri", "http://evil.example.com/callback")
+	resp := oidcRequest(t, st, http.MethodGet, "/oauth/authorize?"+params.Encode(), nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// PKCE is required.
+	params = authorizeParams(clientID, verifier, "")
+	params.Del("code_challenge")
+	resp = oidcRequest(t, st, http.MethodGet, "/oauth/authorize?"+params.Encode(), nil)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Location"), "error=invalid_request")
+
+	params = authorizeParams(clientID, verifier, "")
+	params.Set("phone", phone)
+	params.Set("password", pass)
+	resp = oidcRequest(t, st, http.MethodPost, "/oauth/authorize", params)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	resp = oidcRequest(t, st, http.MethodPost, "/oauth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {location.Query().Get("code")},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {clientID},
+		"code_verifier": {gofakeit.LetterN(64)},
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var oauthErr struct {
+		Error string `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&oauthErr))
+	assert.Equal(t, "invalid_grant", oauthErr.Error)
+
+	resp = oidcRequest(t, st, http.MethodPost, "/oauth/token", url.Values{
+		"grant_type": {"authorization_code"},
+		"client_id":  {"unknown"},
+	})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+// registerClient registers a public OAuth client of the test app directly in the storage,
+// as cmd/register-client does, and returns its ID.
+func registerClient(t *testing.T, st *suite.Suite) string {
+	t.Helper()
+
+	storage, err := sqlite.New(filepath.Join("..", st.Cfg.StoragePath))
+	require.NoError(t, err)
+	defer storage.Stop()
+
+	clientID := gofakeit.UUID()
+
+	require.NoError(t, storage.SaveOAuthClient(context.Background(), models.OAuthClient{
+		ID:           clientID,
+		AppID:        appID,
+		Name:         "Test client",
+		RedirectURIs: []string{redirectURI},
+	}))
+
+	return clientID
+}
+
+func authorizeParams(clientID string, verifier string, nonce string) url.Values {
+	challenge := sha256.Sum256([]byte(verifier))
+
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {"openid phone"},
+		"state":                 {"state-1"},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+// oidcRequest sends the request to the REST server without following redirects. Form is sent URL-encoded.
+func oidcRequest(t *testing.T, st *suite.Suite, method string, path string, form url.Values) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, fmt.Sprintf("http://localhost:%d%s", st.Cfg.REST.Port, path),
+		strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
@@ -0,0 +1,119 @@
+package suite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"io"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"sso/internal/app"
+	"sso/internal/config"
+	"testing"
+	"time"
+)
+
+// NewServer starts a dedicated instance of the service for the test and returns the suite connected to it.
+// The instance has its own ports and storage and the test config changed by configure, so that a test can use
+// settings the shared server can't have, e.g. limits every test would otherwise hit from the same IP.
+// Files of the storage are not shared, so helpers opening st.Cfg.StoragePath relative to the repository don't work.
+func NewServer(t *testing.T, configure func(cfg *config.Config)) (context.Context, *Suite) {
+	t.Helper()
+	t.Parallel()
+
+	cfg := config.MustLoadPath(configPath())
+
+	dir := t.TempDir()
+	cfg.StoragePath = filepath.Join(dir, "sso.db")
+	cfg.SMS.FilePath = filepath.Join(dir, "sms.log")
+	cfg.GRPC.Port = freePort(t)
+	cfg.REST.Port = freePort(t)
+	// Paths in the config are relative to the repository root, while tests run in tests/.
+	if cfg.PasswordPolicy.DenylistPath != "" && !filepath.IsAbs(cfg.PasswordPolicy.DenylistPath) {
+		cfg.PasswordPolicy.DenylistPath = filepath.Join("..", cfg.PasswordPolicy.DenylistPath)
+	}
+	configure(cfg)
+
+	migrateStorage(t, cfg.StoragePath, filepath.Join("..", "migrations"), "migrations")
+	migrateStorage(t, cfg.StoragePath, "migrations", "migrations_test")
+
+	application := app.New(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg)
+
+	go func() {
+		application.GRPCServer.MustRun()
+	}()
+
+	go func() {
+		application.RestServer.MustRun()
+	}()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = application.RestServer.Stop(ctx)
+		application.GRPCServer.Stop()
+	})
+
+	waitForPort(t, cfg.GRPC.Port)
+	waitForPort(t, cfg.REST.Port)
+
+	return connect(t, cfg)
+}
+
+// migrateStorage applies the migrations from the directory to the storage, tracking them in the table.
+func migrateStorage(t *testing.T, storagePath string, migrationsPath string, table string) {
+	t.Helper()
+
+	m, err := migrate.New(
+		"file://"+migrationsPath,
+		fmt.Sprintf("sqlite3://%s?x-migrations-table=%s", storagePath, table),
+	)
+	if err != nil {
+		t.Fatalf("failed to read migrations: %v", err)
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+}
+
+// freePort returns a TCP port nothing listens on at the moment.
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// waitForPort waits until the server of the suite listens on the port.
+func waitForPort(t *testing.T, port int) {
+	t.Helper()
+
+	address := net.JoinHostPort(grpcHost, fmt.Sprint(port))
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		conn, err := net.Dial("tcp", address)
+		if err == nil {
+			conn.Close()
+
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("server didn't start listening on %s: %v", address, err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
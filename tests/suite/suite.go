@@ -27,7 +27,12 @@ func New(t *testing.T) (context.Context, *Suite) {
 	t.Helper()
 	t.Parallel()
 
-	cfg := config.MustLoadPath(configPath())
+	return connect(t, config.MustLoadPath(configPath()))
+}
+
+// connect returns the suite of the server running with the config.
+func connect(t *testing.T, cfg *config.Config) (context.Context, *Suite) {
+	t.Helper()
 
 	ctx, cancelCtx := context.WithTimeout(context.Background(), cfg.GRPC.Timeout)
 
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sso/tests/suite"
+	"testing"
+	"time"
+)
+
+func TestUserDeletion_Restore(t *testing.T) {
+	_, st := suite.New(t)
+
+	creds := restCredentials{
+		Phone:    randomFakePhone(),
+		Password: randomFakePassword(),
+		AppID:    appID,
+	}
+
+	resp := restRequest(t, st, http.MethodPost, "/auth/register", "", creds)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	token := restLogin(t, st, creds)
+
+	resp = restRequest(t, st, http.MethodDelete, "/user", token, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodGet, "/user", token, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// The account pending deletion can still log in.
+	token = restLogin(t, st, creds)
+
+	resp = restRequest(t, st, http.MethodGet, "/user", token, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var user struct {
+		DeletedAt *time.Time `json:"deletedAt"`
+		PurgeAt   *time.Time `json:"purgeAt"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&user))
+	require.NotNil(t, user.DeletedAt)
+	require.NotNil(t, user.PurgeAt)
+	assert.WithinDuration(t, user.DeletedAt.Add(st.Cfg.AccountDeletion.GracePeriod), *user.PurgeAt, time.Second)
+
+	resp = restRequest(t, st, http.MethodPost, "/user/restore", token, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = restRequest(t, st, http.MethodGet, "/user", token, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	user.DeletedAt, user.PurgeAt = nil, nil
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&user))
+	assert.Nil(t, user.DeletedAt)
+	assert.Nil(t, user.PurgeAt)
+
+	resp = restRequest(t, st, http.MethodPost, "/user/restore", token, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+// restLogin logs in with the credentials over REST and returns the access token.
+func restLogin(t *testing.T, st *suite.Suite, creds restCredentials) string {
+	t.Helper()
+
+	resp := restRequest(t, st, http.MethodPost, "/auth/login", "", creds)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var tokens struct {
+		AccessToken string `json:"accessToken"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tokens))
+
+	return tokens.AccessToken
+}